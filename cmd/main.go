@@ -1,16 +1,31 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"github.com/SawitProRecruitment/UserService/generated"
 	"github.com/SawitProRecruitment/UserService/handler"
 	"github.com/SawitProRecruitment/UserService/repository"
 	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"os"
+	"time"
+)
+
+const (
+	// estatePurgeInterval is how often the estates past their retention period are purged.
+	estatePurgeInterval = time.Hour
+	// statsSnapshotInterval is how often the daily snapshots of the estate stats are checked for.
+	statsSnapshotInterval = time.Hour
+	// idempotencyKeyPurgeInterval is how often the expired idempotency keys are purged.
+	idempotencyKeyPurgeInterval = time.Hour
 )
 
 type CustomValidator struct {
@@ -22,31 +37,161 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "recompute-stats" {
+		os.Exit(recomputeStats(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "create-organisation" {
+		os.Exit(createOrganisation(os.Args[2:]))
+	}
+
 	e := echo.New()
 
-	e.Validator = &CustomValidator{validator: validator.New()}
+	validate := validator.New()
+	validate.RegisterTagNameFunc(handler.JSONFieldName)
+	e.Validator = &CustomValidator{validator: validate}
+	e.HTTPErrorHandler = handler.ProblemErrorHandler
+
+	srv := newServer()
+	var server generated.ServerInterface = srv
 
-	var server generated.ServerInterface = newServer()
+	cfg := newViperConfig()
+	auth, err := handler.NewAuthMiddleware(newAuthConfig(cfg))
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	// The idempotency keys are scoped to the organisation the auth middleware authenticates
+	idempotency, err := handler.NewIdempotencyMiddleware(srv.Repository, time.Duration(cfg.GetInt("IDEMPOTENCY_KEY_TTL_HOURS"))*time.Hour)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
 
 	generated.RegisterHandlers(e, server)
 	e.Use(middleware.Logger())
+	e.Use(auth)
+	e.Use(idempotency)
+
+	go srv.RunEstatePurge(context.Background(), estatePurgeInterval, e.Logger)
+	go srv.RunStatsSnapshots(context.Background(), statsSnapshotInterval, e.Logger)
+	go srv.RunIdempotencyKeyPurge(context.Background(), idempotencyKeyPurgeInterval, e.Logger)
+
 	e.Logger.Fatal(e.Start(":1323"))
 }
 
+// recomputeStats rebuilds the materialised tree stats from the trees, or only reports the
+// tree counts which don't match them with -check.
+//
+//	main recompute-stats [-estate <estate id>] [-check]
+func recomputeStats(args []string) int {
+	flags := flag.NewFlagSet("recompute-stats", flag.ExitOnError)
+	estate := flags.String("estate", "", "Estate ID to recompute the stats of, every estate when empty")
+	check := flags.Bool("check", false, "Only report the tree counts which don't match the trees")
+	flags.Parse(args)
+
+	var estateID *string
+	if *estate != "" {
+		estateID = estate
+	}
+
+	srv := newServer()
+	ctx := context.Background()
+
+	drift, err := srv.CheckEstateStats(ctx, estateID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to check the estate stats: %v\n", err)
+		return 1
+	}
+	for _, count := range drift {
+		fmt.Printf("estate %s, %s trees of %dm: %d counted, %d planted\n",
+			count.EstateID, count.Status, count.Height, count.TreeCount, count.ActualTreeCount)
+	}
+
+	if *check {
+		if len(drift) > 0 {
+			return 1
+		}
+		fmt.Println("estate stats are consistent with the trees")
+		return 0
+	}
+
+	err = srv.RecomputeEstateStats(ctx, estateID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to recompute the estate stats: %v\n", err)
+		return 1
+	}
+	fmt.Println("estate stats recomputed")
+
+	return 0
+}
+
+// createOrganisation saves a new organisation and prints its ID, which its API keys and tokens
+// are then scoped to.
+//
+//	main create-organisation -name <name>
+func createOrganisation(args []string) int {
+	flags := flag.NewFlagSet("create-organisation", flag.ExitOnError)
+	name := flags.String("name", "", "Name of the organisation")
+	flags.Parse(args)
+
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "the name of the organisation is required")
+		return 2
+	}
+
+	organisation, err := newServer().CreateOrganisation(context.Background(), *name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create the organisation: %v\n", err)
+		return 1
+	}
+	fmt.Println(organisation.ID)
+
+	return 0
+}
+
 func newServer() *handler.Server {
 	cfg := newViperConfig()
 
 	dbCfg := newDatabase(cfg.GetString("DATABASE_URL"))
 	var repo repository.RepositoryInterface = repository.NewRepository(repository.Repository{Db: dbCfg})
 	opts := handler.NewServerOptions{
-		Repository: repo,
+		Repository:      repo,
+		EstateRetention: time.Duration(cfg.GetInt("ESTATE_RETENTION_DAYS")) * 24 * time.Hour,
 	}
 	return handler.NewServer(opts)
 }
 
+// newAuthConfig reads the credentials the API accepts, without any of them every request is rejected.
+func newAuthConfig(cfg *viper.Viper) handler.AuthConfig {
+	apiKeys, err := handler.ParseAPIKeys(cfg.GetString("AUTH_API_KEYS"))
+	if err != nil {
+		panic(fmt.Errorf("invalid AUTH_API_KEYS: %w", err))
+	}
+
+	config := handler.AuthConfig{
+		APIKeys:     apiKeys,
+		JWTIssuer:   cfg.GetString("AUTH_JWT_ISSUER"),
+		JWTAudience: cfg.GetString("AUTH_JWT_AUDIENCE"),
+	}
+	if secret := cfg.GetString("AUTH_JWT_HS256_SECRET"); secret != "" {
+		config.JWTSecret = []byte(secret)
+	}
+	if path := cfg.GetString("AUTH_JWT_RS256_PUBLIC_KEY_FILE"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+		config.JWTPublicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			panic(fmt.Errorf("invalid AUTH_JWT_RS256_PUBLIC_KEY_FILE: %w", err))
+		}
+	}
+
+	return config
+}
+
 func newDatabase(dbURL string) *gorm.DB {
 	db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true,
 	})
 	if err != nil {
 		panic(err)
@@ -65,6 +210,8 @@ func newViperConfig() *viper.Viper {
 	v.SetConfigType("env")
 
 	v.AutomaticEnv()
+	v.SetDefault("ESTATE_RETENTION_DAYS", 30)
+	v.SetDefault("IDEMPOTENCY_KEY_TTL_HOURS", 24)
 
 	v.ReadInConfig()
 
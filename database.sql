@@ -5,22 +5,172 @@
 -- 3. How you name the fields.
 -- In this assignment we will use PostgreSQL as the database.
 
--- This is test table. Remove this table and replace with your own tables. 
+-- Companies using the service, each one only ever sees its own estates.
+CREATE TABLE IF NOT EXISTS organisations (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    name VARCHAR(150) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Organisation of the local development API key in docker-compose.yml.
+INSERT INTO organisations (id, name) VALUES ('5b1e6f0c-2d4a-4c8e-9f3b-7a6d1c2e8f90', 'Local Development')
+ON CONFLICT (id) DO NOTHING;
+
 CREATE TABLE IF NOT EXISTS estates (
     id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    organisation_id UUID NOT NULL REFERENCES organisations(id),
+    name VARCHAR(100),
+    owner VARCHAR(150),
     width INT NOT NULL,
     length INT NOT NULL,
+    -- The estate is anchored on Earth at the outer corner of plot (1, 1), with its x axis
+    -- pointing along the bearing, in degrees clockwise from true north
+    origin_latitude DOUBLE PRECISION CHECK (origin_latitude BETWEEN -90 AND 90),
+    origin_longitude DOUBLE PRECISION CHECK (origin_longitude BETWEEN -180 AND 180),
+    bearing DOUBLE PRECISION NOT NULL DEFAULT 90 CHECK (bearing >= 0 AND bearing < 360),
+    -- Polygon outlining an irregular estate as a JSON array of {"x", "y"} vertices in plots,
+    -- only the plots whose centre is inside of it can be planted
+    boundary JSONB,
+    -- Plots which can be planted, inside of the boundary when there is one, kept along with it
+    -- so that the estates can be filtered and sorted by their plot count
+    plot_count INT NOT NULL,
+    -- Bumped on every update, the ETag the updates are made conditional on
+    version INT NOT NULL DEFAULT 1,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- Deleted estates are kept for a retention period so they can be restored, then purged
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CHECK ((origin_latitude IS NULL) = (origin_longitude IS NULL))
 );
 
+-- Estates are listed within their organisation, newest first by default.
+CREATE INDEX IF NOT EXISTS estates_organisation_id_created_at_idx ON estates (organisation_id, created_at);
+
+-- Lets the purge job find the deleted estates without scanning the live ones.
+CREATE INDEX IF NOT EXISTS estates_deleted_at_idx ON estates (deleted_at) WHERE deleted_at IS NOT NULL;
+
 CREATE TABLE IF NOT EXISTS trees (
     id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
     estate_id UUID NOT NULL,
     horizontal_position INT NOT NULL,
     vertical_position INT NOT NULL,
     height INT NOT NULL,
+    species VARCHAR(100),
+    variety VARCHAR(100),
+    planting_date DATE,
+    health_status VARCHAR(20) NOT NULL DEFAULT 'healthy' CHECK (health_status IN ('healthy', 'stressed', 'diseased', 'pest_infested')),
+    status VARCHAR(10) NOT NULL DEFAULT 'alive' CHECK (status IN ('alive', 'felled', 'dead')),
+    removal_reason VARCHAR(255),
+    removed_at DATE,
+    previous_tree_id UUID UNIQUE REFERENCES trees(id) ON DELETE SET NULL,
+    -- Bumped on every update, the ETag the updates are made conditional on
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (estate_id) REFERENCES estates(id) ON DELETE CASCADE
+);
+
+-- Lets the spatial queries look up a rectangle of plots instead of scanning the estate.
+CREATE INDEX IF NOT EXISTS trees_estate_id_position_idx ON trees (estate_id, vertical_position, horizontal_position);
+
+-- Lets the height stats of an estate be rebuilt with an index only scan, already ordered by height.
+CREATE INDEX IF NOT EXISTS trees_estate_id_status_height_idx ON trees (estate_id, status, height);
+
+-- Materialised height stats of the trees, kept up to date in the same transaction as every write
+-- to the trees. Heights are whole meters, so counting the trees of each height is the finest
+-- histogram, which the count, sum, min, max and percentiles of an estate are derived from.
+-- A database with trees planted before the table existed is filled by the recompute-stats command.
+CREATE TABLE IF NOT EXISTS estate_stats (
+    estate_id UUID NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    height INT NOT NULL,
+    tree_count INT NOT NULL,
+    PRIMARY KEY (estate_id, status, height),
+    FOREIGN KEY (estate_id) REFERENCES estates(id) ON DELETE CASCADE
+);
+
+-- Daily snapshot of the stats of each estate, for the trends of its trees. The height stats are
+-- those of the living trees. Snapshots are taken at most once a day, so taking them again is a no-op.
+CREATE TABLE IF NOT EXISTS estate_stat_snapshots (
+    estate_id UUID NOT NULL,
+    snapshot_date DATE NOT NULL,
+    tree_count INT NOT NULL,
+    felled_count INT NOT NULL,
+    dead_count INT NOT NULL,
+    min_height INT NOT NULL,
+    max_height INT NOT NULL,
+    mean_height DOUBLE PRECISION NOT NULL,
+    stddev_height DOUBLE PRECISION NOT NULL,
+    median_height DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (estate_id, snapshot_date),
+    FOREIGN KEY (estate_id) REFERENCES estates(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS harvests (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    estate_id UUID NOT NULL,
+    tree_id UUID NOT NULL,
+    harvested_on DATE NOT NULL,
+    bunch_count INT NOT NULL CHECK (bunch_count >= 0),
+    weight_kg NUMERIC(10, 2) NOT NULL CHECK (weight_kg >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- A tree is harvested at most once per harvest round
+    UNIQUE (tree_id, harvested_on),
+    FOREIGN KEY (estate_id) REFERENCES estates(id) ON DELETE CASCADE,
+    FOREIGN KEY (tree_id) REFERENCES trees(id) ON DELETE CASCADE
+);
+
+-- Lets the yield of an estate be aggregated over a period without scanning all of its harvests.
+CREATE INDEX IF NOT EXISTS harvests_estate_id_harvested_on_idx ON harvests (estate_id, harvested_on);
+
+CREATE TABLE IF NOT EXISTS divisions (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    estate_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (estate_id, name),
     FOREIGN KEY (estate_id) REFERENCES estates(id) ON DELETE CASCADE
 );
+
+-- A block is a rectangle of plots, the trees belong to the block their position falls in
+-- so they don't have to be reassigned when blocks change.
+CREATE TABLE IF NOT EXISTS blocks (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    estate_id UUID NOT NULL,
+    division_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    x_from INT NOT NULL CHECK (x_from >= 1),
+    x_to INT NOT NULL,
+    y_from INT NOT NULL CHECK (y_from >= 1),
+    y_to INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (estate_id, name),
+    CHECK (x_from <= x_to AND y_from <= y_to),
+    FOREIGN KEY (estate_id) REFERENCES estates(id) ON DELETE CASCADE,
+    FOREIGN KEY (division_id) REFERENCES divisions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS blocks_division_id_idx ON blocks (division_id);
+
+-- Responses of the create requests sent with an Idempotency-Key, replayed when they are retried.
+-- A key without a status code is held by a request still being processed.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    organisation_id UUID NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    -- SHA-256 of the method, path and body of the request, a key can't be reused for another one
+    request_hash CHAR(64) NOT NULL,
+    status_code INT,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (organisation_id, idempotency_key),
+    FOREIGN KEY (organisation_id) REFERENCES organisations(id) ON DELETE CASCADE
+);
+
+-- Lets the purge job find the expired keys.
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
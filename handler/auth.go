@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+// Roles a caller can be granted, each one allowed everything the previous ones are.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Security schemes of the OpenAPI spec, the way a caller authenticates.
+const (
+	apiKeyScheme = "ApiKeyAuth"
+	bearerScheme = "BearerAuth"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+var errNoCredentials = errors.New("no credentials")
+
+// Principal is who a request is authenticated as.
+type Principal struct {
+	Role string
+	// OrganisationID is the organisation the request is scoped to, it never sees the estates of
+	// another organisation.
+	OrganisationID string
+}
+
+// AuthConfig is the credentials the API accepts.
+type AuthConfig struct {
+	// APIKeys maps each static API key to who it authenticates as.
+	APIKeys map[string]Principal
+	// JWTSecret verifies the HS256 tokens and JWTPublicKey the RS256 ones, the tokens signed
+	// with an algorithm without a key are rejected.
+	JWTSecret    []byte
+	JWTPublicKey *rsa.PublicKey
+	// JWTIssuer and JWTAudience are checked against the claims of the tokens when set.
+	JWTIssuer   string
+	JWTAudience string
+}
+
+// ParseAPIKeys parses API keys configured as a comma separated list of key:role:organisation_id
+// entries.
+func ParseAPIKeys(config string) (map[string]Principal, error) {
+	keys := map[string]Principal{}
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// The key itself may hold colons, the role and the organisation are taken from the end
+		separator := strings.LastIndex(entry, ":")
+		if separator < 1 {
+			return nil, fmt.Errorf("API key without an organisation")
+		}
+		rest, organisationID := entry[:separator], entry[separator+1:]
+		separator = strings.LastIndex(rest, ":")
+		if separator < 1 {
+			return nil, fmt.Errorf("API key without a role")
+		}
+		key, role := rest[:separator], rest[separator+1:]
+
+		if _, ok := roleRanks[role]; !ok {
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+		if _, err := uuid.Parse(organisationID); err != nil {
+			return nil, fmt.Errorf("invalid organisation %q", organisationID)
+		}
+		keys[key] = Principal{Role: role, OrganisationID: organisationID}
+	}
+
+	return keys, nil
+}
+
+// operationRoles returns the role each security scheme needs for each operation of the spec,
+// keyed by method and echo route. An operation without any security scheme is public, while a
+// scheme listed without any scope only needs the viewer role so that it never makes it public.
+// A scope which isn't a role is an error rather than being skipped.
+func operationRoles(swagger *openapi3.T) (map[string]map[string]string, error) {
+	roles := map[string]map[string]string{}
+	for path, item := range swagger.Paths {
+		// The routes are registered with echo's path parameters
+		route := strings.NewReplacer("{", ":", "}", "").Replace(path)
+		for method, operation := range item.Operations() {
+			requirements := swagger.Security
+			if operation.Security != nil {
+				requirements = *operation.Security
+			}
+
+			schemes := map[string]string{}
+			for _, requirement := range requirements {
+				for scheme, scopes := range requirement {
+					role := RoleViewer
+					if len(scopes) > 0 {
+						role = scopes[0]
+					}
+					if _, ok := roleRanks[role]; !ok {
+						return nil, fmt.Errorf("unknown role %q required by %s %s", role, method, path)
+					}
+					schemes[scheme] = role
+				}
+			}
+			roles[method+" "+route] = schemes
+		}
+	}
+
+	return roles, nil
+}
+
+// NewAuthMiddleware authenticates the requests with an API key or a JWT bearer token, checks
+// they are granted the role the security requirements of the operation need in the spec, and
+// scopes the repository to their organisation through the context of the request.
+// Requests which don't match any operation are left to the router to reject.
+func NewAuthMiddleware(config AuthConfig) (echo.MiddlewareFunc, error) {
+	swagger, err := generated.GetSwagger()
+	if err != nil {
+		return nil, err
+	}
+	roles, err := operationRoles(swagger)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			schemes, ok := roles[ctx.Request().Method+" "+ctx.Path()]
+			if !ok || len(schemes) == 0 {
+				return next(ctx)
+			}
+
+			req := ctx.Request()
+			scheme, principal, err := config.authenticate(req)
+			if err != nil {
+				ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return problem(ctx, http.StatusUnauthorized, "unauthorized", "Missing or invalid credentials")
+			}
+
+			required, ok := schemes[scheme]
+			if !ok || roleRanks[principal.Role] < roleRanks[required] {
+				return problem(ctx, http.StatusForbidden, "forbidden", "The credentials don't grant the role the operation needs")
+			}
+
+			ctx.SetRequest(req.WithContext(repository.WithOrganisation(req.Context(), principal.OrganisationID)))
+
+			return next(ctx)
+		}
+	}, nil
+}
+
+// authenticate returns the security scheme a request is authenticated with and who it is
+// authenticated as, the API key taking precedence over the bearer token.
+func (c AuthConfig) authenticate(req *http.Request) (scheme string, principal Principal, err error) {
+	if key := req.Header.Get(apiKeyHeader); key != "" {
+		principal, err = c.apiKeyPrincipal(key)
+		return apiKeyScheme, principal, err
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok || token == "" {
+		return "", Principal{}, errNoCredentials
+	}
+	principal, err = c.tokenPrincipal(token)
+
+	return bearerScheme, principal, err
+}
+
+func (c AuthConfig) apiKeyPrincipal(key string) (principal Principal, err error) {
+	// Every key is compared so the time taken doesn't tell how close a guess is
+	for configured, configuredPrincipal := range c.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(configured)) == 1 {
+			principal = configuredPrincipal
+		}
+	}
+	if principal.Role == "" {
+		err = errors.New("unknown API key")
+	}
+
+	return
+}
+
+func (c AuthConfig) tokenPrincipal(token string) (principal Principal, err error) {
+	// A token which never expires can't be revoked, so the expiry is required
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if c.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(c.JWTIssuer))
+	}
+	if c.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(c.JWTAudience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.NewParser(options...).ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		switch {
+		case token.Method == jwt.SigningMethodHS256 && c.JWTSecret != nil:
+			return c.JWTSecret, nil
+		case token.Method == jwt.SigningMethodRS256 && c.JWTPublicKey != nil:
+			return c.JWTPublicKey, nil
+		}
+		return nil, fmt.Errorf("no key for %s tokens", token.Method.Alg())
+	})
+	if err != nil {
+		return
+	}
+
+	principal.Role, _ = claims["role"].(string)
+	if _, ok := roleRanks[principal.Role]; !ok {
+		return Principal{}, fmt.Errorf("unknown role %q", principal.Role)
+	}
+	principal.OrganisationID, _ = claims["org"].(string)
+	if _, err := uuid.Parse(principal.OrganisationID); err != nil {
+		return Principal{}, fmt.Errorf("invalid organisation %q", principal.OrganisationID)
+	}
+
+	return
+}
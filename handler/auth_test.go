@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// Organisations the credentials of the tests belong to.
+const (
+	organisationID      = "3a9c1b7e-5a34-4d3e-9f0a-2d8b6c1e4f70"
+	otherOrganisationID = "8e2f4c61-0b7d-4a59-a3c8-71d5e9f2b046"
+)
+
+func (e *EndpointsTestSuite) TestParseAPIKeys() {
+	tests := []struct {
+		name           string
+		config         string
+		expectedResult map[string]Principal
+		expectedErr    error
+	}{
+		{
+			name:           "Empty",
+			config:         "",
+			expectedResult: map[string]Principal{},
+		},
+		{
+			name:   "Keys with their role and organisation",
+			config: "viewer-key:viewer:" + organisationID + ", admin:key:admin:" + otherOrganisationID + ",",
+			expectedResult: map[string]Principal{
+				"viewer-key": {Role: RoleViewer, OrganisationID: organisationID},
+				"admin:key":  {Role: RoleAdmin, OrganisationID: otherOrganisationID},
+			},
+		},
+		{
+			name:        "Key without an organisation",
+			config:      "viewer-key",
+			expectedErr: errors.New("API key without an organisation"),
+		},
+		{
+			name:        "Key without a role",
+			config:      "viewer-key:" + organisationID,
+			expectedErr: errors.New("API key without a role"),
+		},
+		{
+			name:        "Unknown role",
+			config:      "owner-key:owner:" + organisationID,
+			expectedErr: errors.New(`unknown role "owner"`),
+		},
+		{
+			name:        "Invalid organisation",
+			config:      "viewer-key:viewer:sawitpro",
+			expectedErr: errors.New(`invalid organisation "sawitpro"`),
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			actualResult, actualErr := ParseAPIKeys(test.config)
+
+			assert.Equal(e.T(), test.expectedErr, actualErr)
+			assert.Equal(e.T(), test.expectedResult, actualResult)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestAuthMiddleware() {
+	type args struct {
+		method string
+		path   string
+		header http.Header
+	}
+
+	secret := []byte("hs256-secret")
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(e.T(), err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(e.T(), err)
+
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) http.Header {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		assert.NoError(e.T(), err)
+		return http.Header{echo.HeaderAuthorization: {"Bearer " + token}}
+	}
+	apiKey := func(key string) http.Header {
+		header := http.Header{}
+		header.Set(apiKeyHeader, key)
+		return header
+	}
+	expiry := time.Now().Add(time.Hour).Unix()
+
+	middleware, err := NewAuthMiddleware(AuthConfig{
+		APIKeys: map[string]Principal{
+			"viewer-key": {Role: RoleViewer, OrganisationID: organisationID},
+			"editor-key": {Role: RoleEditor, OrganisationID: organisationID},
+			"admin-key":  {Role: RoleAdmin, OrganisationID: otherOrganisationID},
+		},
+		JWTSecret:    secret,
+		JWTPublicKey: &privateKey.PublicKey,
+		JWTIssuer:    "https://auth.sawitpro.com",
+	})
+	assert.NoError(e.T(), err)
+
+	router := echo.New()
+	router.Use(middleware)
+	// The handlers answer with the organisation the repository is scoped to
+	ok := func(ctx echo.Context) error {
+		organisationID, _ := repository.OrganisationFromContext(ctx.Request().Context())
+		return ctx.String(http.StatusOK, organisationID)
+	}
+	router.GET("/estate/:estate_id", ok)
+	router.POST("/estate", ok)
+	router.DELETE("/estate/:estate_id", ok)
+
+	tests := []struct {
+		name                   string
+		args                   args
+		expectedStatusCode     int
+		expectedOrganisationID string
+	}{
+		{
+			name:               "Failed, without credentials",
+			args:               args{method: http.MethodGet, path: "/estate/1"},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, unknown API key",
+			args:               args{method: http.MethodGet, path: "/estate/1", header: apiKey("unknown-key")},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, viewer API key creating",
+			args:               args{method: http.MethodPost, path: "/estate", header: apiKey("viewer-key")},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "Failed, editor API key deleting",
+			args:               args{method: http.MethodDelete, path: "/estate/1", header: apiKey("editor-key")},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "Failed, token without an expiry",
+			args:               args{method: http.MethodGet, path: "/estate/1", header: sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"org": organisationID, "role": RoleViewer, "iss": "https://auth.sawitpro.com"})},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, expired token",
+			args:               args{method: http.MethodGet, path: "/estate/1", header: sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"org": organisationID, "role": RoleViewer, "iss": "https://auth.sawitpro.com", "exp": time.Now().Add(-time.Minute).Unix()})},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, token from another issuer",
+			args:               args{method: http.MethodGet, path: "/estate/1", header: sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"org": organisationID, "role": RoleViewer, "iss": "https://example.com", "exp": expiry})},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, token with an unknown role",
+			args:               args{method: http.MethodGet, path: "/estate/1", header: sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"org": organisationID, "role": "owner", "iss": "https://auth.sawitpro.com", "exp": expiry})},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, token signed with another key",
+			args:               args{method: http.MethodGet, path: "/estate/1", header: sign(jwt.SigningMethodRS256, otherKey, jwt.MapClaims{"org": organisationID, "role": RoleViewer, "iss": "https://auth.sawitpro.com", "exp": expiry})},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, unsigned token",
+			args:               args{method: http.MethodGet, path: "/estate/1", header: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.MapClaims{"org": organisationID, "role": RoleAdmin, "iss": "https://auth.sawitpro.com", "exp": expiry})},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, token without an organisation",
+			args:               args{method: http.MethodGet, path: "/estate/1", header: sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"role": RoleViewer, "iss": "https://auth.sawitpro.com", "exp": expiry})},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Failed, editor token deleting",
+			args:               args{method: http.MethodDelete, path: "/estate/1", header: sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"org": organisationID, "role": RoleEditor, "iss": "https://auth.sawitpro.com", "exp": expiry})},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:                   "Success, viewer API key reading",
+			args:                   args{method: http.MethodGet, path: "/estate/1", header: apiKey("viewer-key")},
+			expectedStatusCode:     http.StatusOK,
+			expectedOrganisationID: organisationID,
+		},
+		{
+			name:                   "Success, admin API key of another organisation deleting",
+			args:                   args{method: http.MethodDelete, path: "/estate/1", header: apiKey("admin-key")},
+			expectedStatusCode:     http.StatusOK,
+			expectedOrganisationID: otherOrganisationID,
+		},
+		{
+			name:                   "Success, HS256 editor token creating",
+			args:                   args{method: http.MethodPost, path: "/estate", header: sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"org": organisationID, "role": RoleEditor, "iss": "https://auth.sawitpro.com", "exp": expiry})},
+			expectedStatusCode:     http.StatusOK,
+			expectedOrganisationID: organisationID,
+		},
+		{
+			name:                   "Success, RS256 admin token of another organisation deleting",
+			args:                   args{method: http.MethodDelete, path: "/estate/1", header: sign(jwt.SigningMethodRS256, privateKey, jwt.MapClaims{"org": otherOrganisationID, "role": RoleAdmin, "iss": "https://auth.sawitpro.com", "exp": expiry})},
+			expectedStatusCode:     http.StatusOK,
+			expectedOrganisationID: otherOrganisationID,
+		},
+		{
+			name:               "Success, unknown routes left to the router",
+			args:               args{method: http.MethodGet, path: "/unknown"},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(test.args.method, test.args.path, nil)
+			for name, values := range test.args.header {
+				req.Header[name] = values
+			}
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			if test.expectedStatusCode == http.StatusUnauthorized {
+				assert.Equal(e.T(), "Bearer", rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+			if test.expectedStatusCode == http.StatusOK {
+				assert.Equal(e.T(), test.expectedOrganisationID, rec.Body.String())
+			}
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestAuthMiddlewareWithoutHS256Secret() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(e.T(), err)
+
+	middleware, err := NewAuthMiddleware(AuthConfig{JWTPublicKey: &privateKey.PublicKey})
+	assert.NoError(e.T(), err)
+
+	router := echo.New()
+	router.Use(middleware)
+	router.GET("/estate/:estate_id", func(ctx echo.Context) error { return ctx.NoContent(http.StatusOK) })
+
+	// An HS256 token signed with the public key must not pass for an RS256 one
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.NoError(e.T(), err)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"org": organisationID, "role": RoleAdmin, "exp": time.Now().Add(time.Hour).Unix()}).
+		SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoError(e.T(), err)
+
+	req := httptest.NewRequest(http.MethodGet, "/estate/1", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(e.T(), http.StatusUnauthorized, rec.Code)
+}
+
+func (e *EndpointsTestSuite) TestOperationRoles() {
+	swagger, err := generated.GetSwagger()
+	assert.NoError(e.T(), err)
+
+	roles, err := operationRoles(swagger)
+	assert.NoError(e.T(), err)
+
+	assert.Equal(e.T(), map[string]string{apiKeyScheme: RoleViewer, bearerScheme: RoleViewer}, roles["GET /estate/:estate_id/map.png"])
+	assert.Equal(e.T(), map[string]string{apiKeyScheme: RoleEditor, bearerScheme: RoleEditor}, roles["POST /estate/:estate_id/tree/:tree_id/fell"])
+	assert.Equal(e.T(), map[string]string{apiKeyScheme: RoleAdmin, bearerScheme: RoleAdmin}, roles["DELETE /estate/:estate_id"])
+	// Every operation needs credentials
+	for route, schemes := range roles {
+		assert.NotEmpty(e.T(), schemes, route)
+	}
+}
+
+func (e *EndpointsTestSuite) TestOperationRolesWithoutScopes() {
+	operation := openapi3.NewOperation()
+	operation.Security = &openapi3.SecurityRequirements{{apiKeyScheme: []string{}}}
+	swagger := &openapi3.T{Paths: openapi3.Paths{"/estate/{estate_id}": &openapi3.PathItem{Get: operation}}}
+
+	roles, err := operationRoles(swagger)
+	assert.NoError(e.T(), err)
+	// A scheme without scopes still needs credentials
+	assert.Equal(e.T(), map[string]string{apiKeyScheme: RoleViewer}, roles["GET /estate/:estate_id"])
+
+	operation.Security = &openapi3.SecurityRequirements{{apiKeyScheme: []string{"owner"}}}
+	_, err = operationRoles(swagger)
+	assert.Error(e.T(), err)
+}
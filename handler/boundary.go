@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"math"
+	"slices"
+)
+
+// plotRun is a range of consecutive plots of a row, from and to included.
+type plotRun struct {
+	from int
+	to   int
+}
+
+// plotMask rasterises the boundary of an estate into the plots which can be planted,
+// a plot being inside when its centre is inside the boundary. Estates without a boundary
+// have all of their plots inside. The mask covers the plots from xFrom to xTo and from
+// yFrom to yTo, which is either the whole estate or one of its blocks.
+type plotMask struct {
+	xFrom    int
+	xTo      int
+	yFrom    int
+	yTo      int
+	boundary repository.Boundary
+}
+
+func newPlotMask(estate repository.Estate) plotMask {
+	return plotMask{
+		xFrom:    1,
+		xTo:      estate.Length,
+		yFrom:    1,
+		yTo:      estate.Width,
+		boundary: estate.Boundary,
+	}
+}
+
+// within narrows the mask down to the plots of a block.
+func (m plotMask) within(block repository.Block) plotMask {
+	m.xFrom, m.xTo = max(m.xFrom, block.XFrom), min(m.xTo, block.XTo)
+	m.yFrom, m.yTo = max(m.yFrom, block.YFrom), min(m.yTo, block.YTo)
+	return m
+}
+
+// rowRuns returns the plots of a row which are inside the boundary, in ascending order.
+// The boundary is crossed along the centre line of the row, with the even-odd rule.
+func (m plotMask) rowRuns(y int) (runs []plotRun) {
+	if y < m.yFrom || y > m.yTo || m.xFrom > m.xTo {
+		return
+	}
+	if m.boundary == nil {
+		return []plotRun{{from: m.xFrom, to: m.xTo}}
+	}
+
+	centre := float64(y) - 0.5
+	var crossings []float64
+	for i, a := range m.boundary {
+		b := m.boundary[(i+1)%len(m.boundary)]
+		if (a.Y > centre) != (b.Y > centre) {
+			crossings = append(crossings, a.X+(centre-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+		}
+	}
+	slices.Sort(crossings)
+
+	for i := 0; i+1 < len(crossings); i += 2 {
+		// The centre of plot x is at x-0.5
+		run := plotRun{
+			from: max(int(math.Ceil(crossings[i]+0.5)), m.xFrom),
+			to:   min(int(math.Floor(crossings[i+1]+0.5)), m.xTo),
+		}
+		if run.from > run.to {
+			continue
+		}
+		// Two parts of the boundary touching on the centre of a plot would count it twice
+		if len(runs) > 0 && run.from <= runs[len(runs)-1].to {
+			runs[len(runs)-1].to = max(runs[len(runs)-1].to, run.to)
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	return
+}
+
+func (m plotMask) contains(x int, y int) bool {
+	for _, run := range m.rowRuns(y) {
+		if x >= run.from && x <= run.to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// plotPlantable tells whether a plot can be planted in an estate, for the repository to check
+// the trees against the estate as it is when they are saved.
+func plotPlantable(estate repository.Estate, x int, y int) bool {
+	return newPlotMask(estate).contains(x, y)
+}
+
+func (m plotMask) plotCount() (count int) {
+	if m.boundary == nil {
+		return max(m.xTo-m.xFrom+1, 0) * max(m.yTo-m.yFrom+1, 0)
+	}
+
+	for y := m.yFrom; y <= m.yTo; y++ {
+		for _, run := range m.rowRuns(y) {
+			count += run.to - run.from + 1
+		}
+	}
+
+	return
+}
+
+// dronePathDistance returns the horizontal distance in meters of the drone flying over the
+// plots inside the mask. Without a boundary it flies from the first plot to the farthest one along
+// each axis, the farthest plot of each axis not being counted. With one, the rows without any plot
+// inside are skipped and only the columns spanned by the plots inside are flown over, so a boundary
+// covering the whole estate is flown over the same as none.
+func (m plotMask) dronePathDistance() int {
+	if m.boundary == nil {
+		return (max(m.xTo-m.xFrom, 0) + max(m.yTo-m.yFrom, 0)) * plotMeters
+	}
+
+	xFrom, xTo, rows := m.xTo, m.xFrom, 0
+	for y := m.yFrom; y <= m.yTo; y++ {
+		runs := m.rowRuns(y)
+		if len(runs) == 0 {
+			continue
+		}
+		xFrom, xTo = min(xFrom, runs[0].from), max(xTo, runs[len(runs)-1].to)
+		rows++
+	}
+	if rows == 0 {
+		return 0
+	}
+
+	return (xTo - xFrom + rows - 1) * plotMeters
+}
+
+// boundaryFromRequest converts the boundary of a request, which must be a simple polygon, its
+// edges not crossing nor touching each other, inside of the estate leaving at least a plot to
+// be planted.
+func boundaryFromRequest(vertices []generated.BoundaryVertex, estate repository.Estate) (boundary repository.Boundary, ok bool) {
+	if len(vertices) < 3 {
+		return nil, false
+	}
+
+	boundary = make(repository.Boundary, 0, len(vertices))
+	for _, vertex := range vertices {
+		boundary = append(boundary, repository.BoundaryVertex{X: vertex.X, Y: vertex.Y})
+	}
+	if !boundaryIsSimple(boundary) || !boundaryFits(boundary, estate) {
+		return nil, false
+	}
+
+	return boundary, true
+}
+
+// boundaryIsSimple reports whether no edge of a boundary crosses or touches another one, other
+// than each edge sharing its ends with the edges next to it without folding back over them.
+func boundaryIsSimple(boundary repository.Boundary) bool {
+	n := len(boundary)
+	for i := 0; i < n; i++ {
+		a, b, c := boundary[i], boundary[(i+1)%n], boundary[(i+2)%n]
+		if cross(a, b, c) == 0 && (a.X-b.X)*(c.X-b.X)+(a.Y-b.Y)*(c.Y-b.Y) > 0 {
+			return false
+		}
+
+		// The first edge is next to the last one, which so is only checked against the others
+		last := n - 1
+		if i == 0 {
+			last = n - 2
+		}
+		for j := i + 2; j <= last; j++ {
+			if edgesIntersect(a, b, boundary[j], boundary[(j+1)%n]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// edgesIntersect reports whether the edges from a to b and from c to d have any point in common.
+func edgesIntersect(a, b, c, d repository.BoundaryVertex) bool {
+	abc, abd, cda, cdb := cross(a, b, c), cross(a, b, d), cross(c, d, a), cross(c, d, b)
+	if ((abc > 0 && abd < 0) || (abc < 0 && abd > 0)) && ((cda > 0 && cdb < 0) || (cda < 0 && cdb > 0)) {
+		return true
+	}
+
+	return (abc == 0 && onEdge(a, b, c)) || (abd == 0 && onEdge(a, b, d)) ||
+		(cda == 0 && onEdge(c, d, a)) || (cdb == 0 && onEdge(c, d, b))
+}
+
+// cross returns the cross product of the vectors from a to b and from a to c, positive when c is
+// on the left of the line from a to b, negative on its right and 0 on it.
+func cross(a, b, c repository.BoundaryVertex) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+// onEdge reports whether c, which is on the line from a to b, is between them.
+func onEdge(a, b, c repository.BoundaryVertex) bool {
+	return min(a.X, b.X) <= c.X && c.X <= max(a.X, b.X) && min(a.Y, b.Y) <= c.Y && c.Y <= max(a.Y, b.Y)
+}
+
+// boundaryFits reports whether a boundary is inside of the estate and leaves at least a plot of
+// it to be planted, which a boundary stops doing when the estate is shrunk under it.
+func boundaryFits(boundary repository.Boundary, estate repository.Estate) bool {
+	for _, vertex := range boundary {
+		if vertex.X < 0 || vertex.X > float64(estate.Length) || vertex.Y < 0 || vertex.Y > float64(estate.Width) {
+			return false
+		}
+	}
+
+	estate.Boundary = boundary
+	for y := 1; y <= estate.Width; y++ {
+		if len(newPlotMask(estate).rowRuns(y)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func boundaryToResponse(boundary repository.Boundary) *[]generated.BoundaryVertex {
+	if boundary == nil {
+		return nil
+	}
+
+	resp := make([]generated.BoundaryVertex, 0, len(boundary))
+	for _, vertex := range boundary {
+		resp = append(resp, generated.BoundaryVertex{X: vertex.X, Y: vertex.Y})
+	}
+
+	return &resp
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func (e *EndpointsTestSuite) TestPlotMask() {
+	type args struct {
+		estate repository.Estate
+	}
+
+	triangle := repository.Boundary{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 0, Y: 5}}
+	// A U shape, with the middle plot of the second row cut out
+	uShape := repository.Boundary{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 2}, {X: 2, Y: 2}, {X: 2, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 0, Y: 2}}
+	// Two rows joined by a neck too narrow for the centre of any plot of the row between them
+	neck := repository.Boundary{
+		{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 1}, {X: 0.9, Y: 1}, {X: 0.9, Y: 2}, {X: 3, Y: 2},
+		{X: 3, Y: 3}, {X: 0, Y: 3}, {X: 0, Y: 2}, {X: 0.6, Y: 2}, {X: 0.6, Y: 1}, {X: 0, Y: 1},
+	}
+
+	tests := []struct {
+		name              string
+		args              args
+		expectedRuns      [][]plotRun
+		expectedPlotCount int
+		expectedDistance  int
+	}{
+		{
+			name: "Without boundary",
+			args: args{
+				estate: repository.Estate{Length: 5, Width: 2},
+			},
+			expectedRuns:      [][]plotRun{{{from: 1, to: 5}}, {{from: 1, to: 5}}},
+			expectedPlotCount: 10,
+			expectedDistance:  50,
+		},
+		{
+			name: "Triangle, the plots with their centre on the boundary are inside",
+			args: args{
+				estate: repository.Estate{Length: 5, Width: 5, Boundary: triangle},
+			},
+			expectedRuns: [][]plotRun{
+				{{from: 1, to: 5}},
+				{{from: 1, to: 4}},
+				{{from: 1, to: 3}},
+				{{from: 1, to: 2}},
+				{{from: 1, to: 1}},
+			},
+			expectedPlotCount: 15,
+			expectedDistance:  80,
+		},
+		{
+			name: "U shape, a row split in two",
+			args: args{
+				estate: repository.Estate{Length: 3, Width: 2, Boundary: uShape},
+			},
+			expectedRuns:      [][]plotRun{{{from: 1, to: 3}}, {{from: 1, to: 1}, {from: 3, to: 3}}},
+			expectedPlotCount: 5,
+			expectedDistance:  30,
+		},
+		{
+			name: "Neck, the row without any plot inside skipped",
+			args: args{
+				estate: repository.Estate{Length: 3, Width: 3, Boundary: neck},
+			},
+			expectedRuns:      [][]plotRun{{{from: 1, to: 3}}, nil, {{from: 1, to: 3}}},
+			expectedPlotCount: 6,
+			// The whole 3x3 estate is flown over in 40 meters
+			expectedDistance: 30,
+		},
+		{
+			name: "Boundary larger than a shrunk estate",
+			args: args{
+				estate: repository.Estate{Length: 2, Width: 1, Boundary: triangle},
+			},
+			expectedRuns:      [][]plotRun{{{from: 1, to: 2}}},
+			expectedPlotCount: 2,
+			expectedDistance:  10,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			mask := newPlotMask(test.args.estate)
+
+			for y, expectedRuns := range test.expectedRuns {
+				assert.Equal(e.T(), expectedRuns, mask.rowRuns(y+1))
+			}
+			assert.Equal(e.T(), test.expectedPlotCount, mask.plotCount())
+			assert.Equal(e.T(), test.expectedDistance, mask.dronePathDistance())
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestPlotMaskContains() {
+	mask := newPlotMask(repository.Estate{
+		Length:   3,
+		Width:    2,
+		Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 2}, {X: 2, Y: 2}, {X: 2, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 0, Y: 2}},
+	})
+
+	assert.True(e.T(), mask.contains(2, 1))
+	assert.True(e.T(), mask.contains(3, 2))
+	assert.False(e.T(), mask.contains(2, 2))
+	assert.False(e.T(), mask.contains(4, 1))
+	assert.False(e.T(), mask.contains(1, 3))
+}
+
+func (e *EndpointsTestSuite) TestPlotPlantable() {
+	estate := repository.Estate{
+		Length:   3,
+		Width:    2,
+		Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 2}, {X: 2, Y: 2}, {X: 2, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 0, Y: 2}},
+	}
+
+	assert.True(e.T(), plotPlantable(estate, 3, 2))
+	assert.False(e.T(), plotPlantable(estate, 2, 2))
+	// The same plot is plantable once the boundary is removed
+	estate.Boundary = nil
+	assert.True(e.T(), plotPlantable(estate, 2, 2))
+	assert.False(e.T(), plotPlantable(estate, 4, 1))
+}
+
+func (e *EndpointsTestSuite) TestPlotMaskWithin() {
+	mask := newPlotMask(repository.Estate{
+		Length:   5,
+		Width:    5,
+		Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 0, Y: 5}},
+	}).within(repository.Block{XFrom: 2, XTo: 6, YFrom: 2, YTo: 3})
+
+	assert.Equal(e.T(), []plotRun(nil), mask.rowRuns(1))
+	assert.Equal(e.T(), []plotRun{{from: 2, to: 4}}, mask.rowRuns(2))
+	assert.Equal(e.T(), []plotRun{{from: 2, to: 3}}, mask.rowRuns(3))
+	assert.Equal(e.T(), 5, mask.plotCount())
+	// Across the plots of the second and third rows inside, from the second to the fourth column
+	assert.Equal(e.T(), 30, mask.dronePathDistance())
+}
+
+func (e *EndpointsTestSuite) TestBoundaryIsSimple() {
+	tests := []struct {
+		name     string
+		boundary repository.Boundary
+		expected bool
+	}{
+		{
+			name:     "Triangle",
+			boundary: repository.Boundary{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 0, Y: 5}},
+			expected: true,
+		},
+		{
+			name:     "U shape",
+			boundary: repository.Boundary{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 2}, {X: 2, Y: 2}, {X: 2, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 0, Y: 2}},
+			expected: true,
+		},
+		{
+			name:     "Bow tie, its edges crossing",
+			boundary: repository.Boundary{{X: 0, Y: 0}, {X: 4, Y: 4}, {X: 4, Y: 0}, {X: 0, Y: 4}},
+			expected: false,
+		},
+		{
+			name:     "Vertex touching an edge",
+			boundary: repository.Boundary{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 2, Y: 0}, {X: 0, Y: 4}},
+			expected: false,
+		},
+		{
+			name:     "Edge folding back over the previous one",
+			boundary: repository.Boundary{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 4}},
+			expected: false,
+		},
+		{
+			name:     "Collinear edges overlapping",
+			boundary: repository.Boundary{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 2}, {X: 3, Y: 2}, {X: 3, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 2}, {X: 0, Y: 2}},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			assert.Equal(e.T(), test.expected, boundaryIsSimple(test.boundary))
+		})
+	}
+}
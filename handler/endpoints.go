@@ -9,6 +9,18 @@ import (
 	openapi_types "github.com/oapi-codegen/runtime/types"
 	"gorm.io/gorm"
 	"net/http"
+	"time"
+)
+
+const (
+	defaultPage     = 1
+	defaultPageSize = 100
+	defaultGridCell = 50
+
+	defaultEstateSort = generated.MinusCreatedAt
+
+	// Each plot is 10x10 meters, so a hectare is 100 plots
+	plotsPerHectare = 100
 )
 
 func stringToUUID(uuidSTR string) (parsedUUID openapi_types.UUID) {
@@ -16,26 +28,157 @@ func stringToUUID(uuidSTR string) (parsedUUID openapi_types.UUID) {
 	return
 }
 
-func (s *Server) CreateEstate(ctx echo.Context) error {
+// gridCellToResponse converts a cell to plot bounds, the cells on the far edges
+// of the estate are cut short by its length and width, and its occupancy only
+// counts the plots of the cell inside of the estate's boundary.
+func gridCellToResponse(gridCell repository.GridCell, cell int, estate repository.Estate) generated.GridCell {
+	xFrom, yFrom := gridCell.CellX*cell+1, gridCell.CellY*cell+1
+	xTo, yTo := min(xFrom+cell-1, estate.Length), min(yFrom+cell-1, estate.Width)
+
+	var occupancy float64
+	block := repository.Block{XFrom: xFrom, XTo: xTo, YFrom: yFrom, YTo: yTo}
+	if plots := newPlotMask(estate).within(block).plotCount(); plots > 0 {
+		occupancy = float64(gridCell.PlantedPlots) / float64(plots)
+	}
+
+	return generated.GridCell{
+		Column:    gridCell.CellX + 1,
+		Row:       gridCell.CellY + 1,
+		XFrom:     xFrom,
+		XTo:       xTo,
+		YFrom:     yFrom,
+		YTo:       yTo,
+		TreeCount: gridCell.TreeCount,
+		AvgHeight: gridCell.AvgHeight,
+		MaxHeight: gridCell.MaxHeight,
+		Occupancy: occupancy,
+	}
+}
+
+func treeToResponse(tree repository.Tree, estate repository.Estate) (resp generated.Tree) {
+	resp = generated.Tree{
+		Id:            stringToUUID(tree.ID),
+		X:             tree.HorizontalPosition,
+		Y:             tree.VerticalPosition,
+		Height:        tree.Height,
+		Species:       tree.Species,
+		Variety:       tree.Variety,
+		HealthStatus:  generated.TreeHealthStatus(tree.HealthStatus),
+		Status:        generated.TreeStatus(tree.Status),
+		RemovalReason: tree.RemovalReason,
+		CreatedAt:     tree.CreatedAt,
+		UpdatedAt:     tree.UpdatedAt,
+	}
+	if tree.PlantingDate != nil {
+		resp.PlantingDate = &openapi_types.Date{Time: *tree.PlantingDate}
+	}
+	if tree.RemovedAt != nil {
+		resp.RemovedAt = &openapi_types.Date{Time: *tree.RemovedAt}
+	}
+	if tree.PreviousTreeID != nil {
+		previousTreeID := stringToUUID(*tree.PreviousTreeID)
+		resp.PreviousTreeId = &previousTreeID
+	}
+	if latitude, longitude, ok := plotPosition(estate, tree.HorizontalPosition, tree.VerticalPosition); ok {
+		resp.Latitude, resp.Longitude = &latitude, &longitude
+	}
+
+	return
+}
+
+func estateToResponse(estate repository.Estate, plantedPlotCount int64) (resp generated.Estate) {
+	plotCount := estate.PlotCount
+	resp = generated.Estate{
+		Id:               stringToUUID(estate.ID),
+		Name:             estate.Name,
+		Owner:            estate.Owner,
+		OriginLatitude:   estate.OriginLatitude,
+		OriginLongitude:  estate.OriginLongitude,
+		Bearing:          estate.Bearing,
+		Boundary:         boundaryToResponse(estate.Boundary),
+		Length:           estate.Length,
+		Width:            estate.Width,
+		PlotCount:        plotCount,
+		AreaHectares:     float64(plotCount) / plotsPerHectare,
+		PlantedPlotCount: int(plantedPlotCount),
+		CreatedAt:        estate.CreatedAt,
+		UpdatedAt:        estate.UpdatedAt,
+	}
+	if plotCount > 0 {
+		resp.Occupancy = float64(plantedPlotCount) / float64(plotCount)
+	}
+
+	return
+}
+
+func blockToResponse(block repository.BlockSummary, estate repository.Estate) generated.Block {
+	return generated.Block{
+		Id:         stringToUUID(block.ID),
+		DivisionId: stringToUUID(block.DivisionID),
+		Name:       block.Name,
+		XFrom:      block.XFrom,
+		XTo:        block.XTo,
+		YFrom:      block.YFrom,
+		YTo:        block.YTo,
+		PlotCount:  newPlotMask(estate).within(block.Block).plotCount(),
+		TreeCount:  block.TreeCount,
+		CreatedAt:  block.CreatedAt,
+	}
+}
+
+func harvestToResponse(harvest repository.Harvest) generated.Harvest {
+	return generated.Harvest{
+		Id:         stringToUUID(harvest.ID),
+		TreeId:     stringToUUID(harvest.TreeID),
+		Date:       openapi_types.Date{Time: harvest.HarvestedOn},
+		BunchCount: harvest.BunchCount,
+		Weight:     harvest.WeightKg,
+		CreatedAt:  harvest.CreatedAt,
+	}
+}
+
+// CreateEstate creates an estate, its Idempotency-Key is handled by the idempotency middleware.
+func (s *Server) CreateEstate(ctx echo.Context, params generated.CreateEstateParams) error {
 	var createReq generated.CreateEstateJSONBody
 	err := ctx.Bind(&createReq)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, generated.InvalidInputErrorResponse{Error: "Invalid input"})
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body is malformed")
 	}
 
 	err = ctx.Validate(createReq)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, generated.InvalidInputErrorResponse{Error: "Invalid input"})
+		return validationProblem(ctx, err)
+	}
+
+	// The origin is a single point, half of it can't be placed on Earth
+	if (createReq.OriginLatitude == nil) != (createReq.OriginLongitude == nil) {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "origin_latitude and origin_longitude must be set together")
 	}
 
 	newEstate := repository.Estate{
-		Width:  createReq.Width,
-		Length: createReq.Length,
+		Name:            createReq.Name,
+		Owner:           createReq.Owner,
+		Width:           createReq.Width,
+		Length:          createReq.Length,
+		OriginLatitude:  createReq.OriginLatitude,
+		OriginLongitude: createReq.OriginLongitude,
+		Bearing:         defaultBearing,
+	}
+	if createReq.Bearing != nil {
+		newEstate.Bearing = *createReq.Bearing
+	}
+	if createReq.Boundary != nil {
+		boundary, ok := boundaryFromRequest(*createReq.Boundary, newEstate)
+		if !ok {
+			return problem(ctx, http.StatusBadRequest, "invalid_input", "Boundary must be a simple polygon inside of the estate leaving a plot to plant")
+		}
+		newEstate.Boundary = boundary
 	}
+	newEstate.PlotCount = newPlotMask(newEstate).plotCount()
 
 	err = s.Repository.CreateEstate(ctx.Request().Context(), &newEstate)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, generated.InternalServerErrorResponse{Error: "Oops, something wrong with the server. Please try again later"})
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
 	}
 
 	resp := generated.CreateEstateResponse{
@@ -45,29 +188,290 @@ func (s *Server) CreateEstate(ctx echo.Context) error {
 	return ctx.JSON(http.StatusCreated, resp)
 }
 
-func (s *Server) CreateTree(ctx echo.Context, estateID openapi_types.UUID) error {
+func (s *Server) GetEstate(ctx echo.Context, estateID openapi_types.UUID) error {
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	plantedPlotCount, err := s.Repository.GetPlantedPlotCountByEstateID(ctx.Request().Context(), estate.ID)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	setVersionETag(ctx, estate.Version)
+
+	return ctx.JSON(http.StatusOK, estateToResponse(estate, plantedPlotCount))
+}
+
+// DeleteEstate deletes an estate only when it wasn't changed since the If-Match ETag was read.
+func (s *Server) DeleteEstate(ctx echo.Context, estateID openapi_types.UUID, params generated.DeleteEstateParams) error {
+	if params.IfMatch == nil || *params.IfMatch == "" {
+		return problem(ctx, http.StatusPreconditionRequired, "precondition_required", "If-Match header is required, send the ETag the estate was last read at")
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	if !ifMatch(*params.IfMatch, estate.Version) {
+		return problem(ctx, http.StatusPreconditionFailed, "estate_changed", "Estate was changed since it was read")
+	}
+
+	err = s.Repository.DeleteEstate(ctx.Request().Context(), estate.ID, estate.Version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		// The estate got changed by someone else in the meantime
+		if errors.Is(err, repository.ErrVersionMismatch) {
+			return problem(ctx, http.StatusPreconditionFailed, "estate_changed", "Estate was changed since it was read")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) RestoreEstate(ctx echo.Context, estateID openapi_types.UUID) error {
+	err := s.Repository.RestoreEstate(ctx.Request().Context(), estateID.String(), time.Now().Add(-s.EstateRetention))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "deleted_estate_not_found", "Deleted estate not found or its retention period is over")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	return s.GetEstate(ctx, estateID)
+}
+
+// UpdateEstate updates an estate only when it wasn't changed since the If-Match ETag was read.
+func (s *Server) UpdateEstate(ctx echo.Context, estateID openapi_types.UUID, params generated.UpdateEstateParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	var updateReq generated.UpdateEstateJSONBody
+	err = ctx.Bind(&updateReq)
+	if err != nil {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body is malformed")
+	}
+
+	err = ctx.Validate(updateReq)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+	if updateReq == (generated.UpdateEstateJSONBody{}) {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "No field to update")
+	}
+	if (updateReq.OriginLatitude == nil) != (updateReq.OriginLongitude == nil) {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "origin_latitude and origin_longitude must be set together")
+	}
+
+	if params.IfMatch == nil || *params.IfMatch == "" {
+		return problem(ctx, http.StatusPreconditionRequired, "precondition_required", "If-Match header is required, send the ETag the estate was last read at")
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	if !ifMatch(*params.IfMatch, estate.Version) {
+		return problem(ctx, http.StatusPreconditionFailed, "estate_changed", "Estate was changed since it was read")
+	}
+
+	if updateReq.Name != nil {
+		estate.Name = updateReq.Name
+	}
+	if updateReq.Owner != nil {
+		estate.Owner = updateReq.Owner
+	}
+	length, width := estate.Length, estate.Width
+	if updateReq.Length != nil {
+		estate.Length = *updateReq.Length
+	}
+	if updateReq.Width != nil {
+		estate.Width = *updateReq.Width
+	}
+	if updateReq.OriginLatitude != nil {
+		estate.OriginLatitude, estate.OriginLongitude = updateReq.OriginLatitude, updateReq.OriginLongitude
+	}
+	if updateReq.Bearing != nil {
+		estate.Bearing = *updateReq.Bearing
+	}
+	if updateReq.Boundary != nil {
+		estate.Boundary = nil
+		if len(*updateReq.Boundary) > 0 {
+			boundary, ok := boundaryFromRequest(*updateReq.Boundary, estate)
+			if !ok {
+				return problem(ctx, http.StatusBadRequest, "invalid_input", "Boundary must be a simple polygon inside of the estate leaving a plot to plant")
+			}
+			estate.Boundary = boundary
+		}
+	}
+
+	resized := estate.Length != length || estate.Width != width
+	if updateReq.Boundary == nil && resized && estate.Boundary != nil && !boundaryFits(estate.Boundary, estate) {
+		return problem(ctx, http.StatusBadRequest, "boundary_out_of_estate", "Boundary would be left outside of the estate's area, send a new one along with the size")
+	}
+
+	// The trees left outside of the new bounds are up to the force, the relocated ones only
+	// being moved onto plots inside of the boundary, but not the ones left outside of the
+	// boundary as they can't be moved to a plot of it in a sensible way
+	mask := newPlotMask(estate)
+	estate.PlotCount = mask.plotCount()
+
+	var force string
+	if params.Force != nil {
+		force = string(*params.Force)
+	}
+
+	outOfBounds, blocksOutOfBounds, err := s.Repository.UpdateEstate(ctx.Request().Context(), estate, force, mask.contains)
+	if err != nil {
+		if errors.Is(err, repository.ErrBlocksOutOfBounds) {
+			outside := make([]generated.Block, 0, len(blocksOutOfBounds))
+			for _, block := range blocksOutOfBounds {
+				outside = append(outside, blockToResponse(block, estate))
+			}
+			return blocksOutOfBoundsProblem(ctx, outside)
+		}
+		if errors.Is(err, repository.ErrTreesOutOfBounds) || errors.Is(err, repository.ErrNoFreePlots) {
+			outside := make([]generated.Tree, 0, len(outOfBounds))
+			for _, tree := range outOfBounds {
+				outside = append(outside, treeToResponse(tree, estate))
+			}
+			if errors.Is(err, repository.ErrNoFreePlots) {
+				return treesProblem(ctx, "no_free_plots", "Not enough free plots are left inside of the estate to relocate the trees to", outside)
+			}
+			return treesOutOfBoundsProblem(ctx, outside)
+		}
+		// The estate got deleted or changed by someone else in the meantime
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		if errors.Is(err, repository.ErrVersionMismatch) {
+			return problem(ctx, http.StatusPreconditionFailed, "estate_changed", "Estate was changed since it was read")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+	estate.UpdatedAt = time.Now()
+	estate.Version++
+
+	plantedPlotCount, err := s.Repository.GetPlantedPlotCountByEstateID(ctx.Request().Context(), estate.ID)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	setVersionETag(ctx, estate.Version)
+
+	return ctx.JSON(http.StatusOK, estateToResponse(estate, plantedPlotCount))
+}
+
+func (s *Server) GetEstates(ctx echo.Context, params generated.GetEstatesParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	if params.MinPlotCount != nil && params.MaxPlotCount != nil && *params.MaxPlotCount < *params.MinPlotCount {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "max_plot_count is less than min_plot_count")
+	}
+	if params.CreatedFrom != nil && params.CreatedTo != nil && params.CreatedTo.Before(params.CreatedFrom.Time) {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "created_to is before created_from")
+	}
+
+	page, pageSize, sort := defaultPage, defaultPageSize, defaultEstateSort
+	if params.Page != nil {
+		page = *params.Page
+	}
+	if params.PageSize != nil {
+		pageSize = *params.PageSize
+	}
+	if params.Sort != nil {
+		sort = *params.Sort
+	}
+
+	filter := repository.EstateFilter{
+		Name:         params.Name,
+		MinPlotCount: params.MinPlotCount,
+		MaxPlotCount: params.MaxPlotCount,
+		Sort:         string(sort),
+		Limit:        pageSize,
+		Offset:       (page - 1) * pageSize,
+	}
+	if params.CreatedFrom != nil {
+		filter.CreatedFrom = &params.CreatedFrom.Time
+	}
+	if params.CreatedTo != nil {
+		filter.CreatedTo = &params.CreatedTo.Time
+	}
+
+	estates, total, err := s.Repository.GetEstates(ctx.Request().Context(), filter)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetEstatesResponse{
+		Estates:  make([]generated.EstateSummary, 0, len(estates)),
+		Page:     page,
+		PageSize: pageSize,
+		Total:    int(total),
+	}
+	for _, estate := range estates {
+		resp.Estates = append(resp.Estates, generated.EstateSummary{
+			Id:              stringToUUID(estate.ID),
+			Name:            estate.Name,
+			Owner:           estate.Owner,
+			OriginLatitude:  estate.OriginLatitude,
+			OriginLongitude: estate.OriginLongitude,
+			Bearing:         estate.Bearing,
+			Length:          estate.Length,
+			Width:           estate.Width,
+			PlotCount:       estate.PlotCount,
+			TreeCount:       estate.TreeCount,
+			CreatedAt:       estate.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// CreateTree plants a tree in an estate, its Idempotency-Key is handled by the idempotency middleware.
+func (s *Server) CreateTree(ctx echo.Context, estateID openapi_types.UUID, params generated.CreateTreeParams) error {
 	var createReq generated.CreateTreeJSONBody
 	err := ctx.Bind(&createReq)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, generated.InvalidInputErrorResponse{Error: "Invalid input"})
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body is malformed")
 	}
 
 	err = ctx.Validate(createReq)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, generated.InvalidInputErrorResponse{Error: "Invalid input"})
+		return validationProblem(ctx, err)
 	}
 
 	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ctx.JSON(http.StatusNotFound, generated.NotFoundErrorResponse{Error: "Estate not found"})
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
 		}
-		return ctx.JSON(http.StatusInternalServerError, generated.InternalServerErrorResponse{Error: "Oops, something wrong with the server. Please try again later"})
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
 	}
 
-	// Tree position is out of the estate's area
-	if createReq.X > estate.Length || createReq.Y > estate.Width {
-		return ctx.JSON(http.StatusBadRequest, generated.InvalidInputErrorResponse{Error: "Tree position is out of the estate's area"})
+	// Tree position is out of the estate's area or its boundary
+	if !newPlotMask(estate).contains(createReq.X, createReq.Y) {
+		return problem(ctx, http.StatusBadRequest, "tree_out_of_estate", "Tree position is out of the estate's area")
 	}
 
 	newTree := repository.Tree{
@@ -75,11 +479,25 @@ func (s *Server) CreateTree(ctx echo.Context, estateID openapi_types.UUID) error
 		HorizontalPosition: createReq.X,
 		VerticalPosition:   createReq.Y,
 		Height:             createReq.Height,
+		Species:            createReq.Species,
+		Variety:            createReq.Variety,
+		HealthStatus:       repository.HealthStatusHealthy,
+		Status:             repository.TreeStatusAlive,
+	}
+	if createReq.PlantingDate != nil {
+		newTree.PlantingDate = &createReq.PlantingDate.Time
+	}
+	if createReq.HealthStatus != nil {
+		newTree.HealthStatus = string(*createReq.HealthStatus)
 	}
 
-	err = s.Repository.CreateTree(ctx.Request().Context(), &newTree)
+	err = s.Repository.CreateTree(ctx.Request().Context(), &newTree, plotPlantable)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, generated.InternalServerErrorResponse{Error: "Oops, something wrong with the server. Please try again later"})
+		// The estate was shrunk or had its boundary changed since it was read
+		if errors.Is(err, repository.ErrTreeOutOfEstate) {
+			return problem(ctx, http.StatusBadRequest, "tree_out_of_estate", "Tree position is out of the estate's area")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
 	}
 
 	resp := generated.CreateTreeResponse{
@@ -89,63 +507,1098 @@ func (s *Server) CreateTree(ctx echo.Context, estateID openapi_types.UUID) error
 	return ctx.JSON(http.StatusCreated, resp)
 }
 
-func (s *Server) GetEstateStats(ctx echo.Context, estateID openapi_types.UUID) error {
+func (s *Server) GetTree(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) error {
 	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ctx.JSON(http.StatusNotFound, generated.NotFoundErrorResponse{Error: "Estate not found"})
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
 		}
-		return ctx.JSON(http.StatusInternalServerError, generated.InternalServerErrorResponse{Error: "Oops, something wrong with the server. Please try again later"})
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
 	}
 
-	trees, err := s.Repository.GetTreeHeightsByEstateID(ctx.Request().Context(), estate.ID)
+	tree, err := s.Repository.GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String())
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, generated.InternalServerErrorResponse{Error: "Oops, something wrong with the server. Please try again later"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "tree_not_found", "Tree not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
 	}
 
-	var resp generated.GetEstateStatsResponse
-	treeCount := len(trees)
-	if treeCount > 0 {
-		resp.Count = treeCount
-		resp.Max = trees[treeCount-1]
-		resp.Min = trees[0]
+	setVersionETag(ctx, tree.Version)
+
+	return ctx.JSON(http.StatusOK, treeToResponse(tree, estate))
+}
 
-		if treeCount%2 == 0 {
-			resp.Median = (trees[treeCount/2-1] + trees[treeCount/2]) / 2 // the div result will be floored
-		} else {
-			resp.Median = trees[treeCount/2]
+func (s *Server) GetTreeHistory(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) error {
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
 		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	trees, err := s.Repository.GetTreeLineageByID(ctx.Request().Context(), estateID.String(), treeID.String())
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	if len(trees) == 0 {
+		return problem(ctx, http.StatusNotFound, "tree_not_found", "Tree not found")
+	}
+
+	resp := generated.GetTreeHistoryResponse{
+		Trees: make([]generated.Tree, 0, len(trees)),
+	}
+	for _, tree := range trees {
+		resp.Trees = append(resp.Trees, treeToResponse(tree, estate))
 	}
 
 	return ctx.JSON(http.StatusOK, resp)
 }
 
-func (s *Server) GetEstateDronePlan(ctx echo.Context, estateId openapi_types.UUID) error {
-	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateId.String())
+// FellTree fells a tree only when it wasn't changed since the If-Match ETag was read.
+func (s *Server) FellTree(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID, params generated.FellTreeParams) error {
+	var fellReq generated.FellTreeJSONBody
+	err := ctx.Bind(&fellReq)
+	if err != nil {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body is malformed")
+	}
+
+	err = ctx.Validate(fellReq)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	if params.IfMatch == nil || *params.IfMatch == "" {
+		return problem(ctx, http.StatusPreconditionRequired, "precondition_required", "If-Match header is required, send the ETag the tree was last read at")
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ctx.JSON(http.StatusNotFound, generated.NotFoundErrorResponse{Error: "Estate not found"})
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
 		}
-		return ctx.JSON(http.StatusInternalServerError, generated.InternalServerErrorResponse{Error: "Oops, something wrong with the server. Please try again later"})
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
 	}
 
-	trees, err := s.Repository.GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estate.ID)
+	tree, err := s.Repository.GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String())
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, generated.InternalServerErrorResponse{Error: "Oops, something wrong with the server. Please try again later"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "tree_not_found", "Tree not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
 	}
 
-	var resp generated.GetEstateDronePlanResponse
-	if len(trees) > 0 {
-		var totalTreeHeight int
-		for _, tree := range trees {
-			totalTreeHeight += tree.Height
+	if !ifMatch(*params.IfMatch, tree.Version) {
+		return problem(ctx, http.StatusPreconditionFailed, "tree_changed", "Tree was changed since it was read")
+	}
+
+	if tree.Status != repository.TreeStatusAlive {
+		return problem(ctx, http.StatusConflict, "tree_not_alive", "Tree is already felled or dead")
+	}
+
+	status := repository.TreeStatusFelled
+	if fellReq.Status != nil {
+		status = string(*fellReq.Status)
+	}
+
+	removedAt := time.Now().UTC().Truncate(24 * time.Hour)
+	if fellReq.Date != nil {
+		removedAt = fellReq.Date.Time
+	}
+
+	err = s.Repository.FellTree(ctx.Request().Context(), tree.ID, tree.Version, status, fellReq.Reason, removedAt)
+	if err != nil {
+		// The tree got felled or changed by someone else in the meantime
+		if errors.Is(err, repository.ErrTreeNotAlive) {
+			return problem(ctx, http.StatusConflict, "tree_not_alive", "Tree is already felled or dead")
 		}
-		resp.Distance += totalTreeHeight
+		if errors.Is(err, repository.ErrVersionMismatch) {
+			return problem(ctx, http.StatusPreconditionFailed, "tree_changed", "Tree was changed since it was read")
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "tree_not_found", "Tree not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
 	}
 
-	// 2 is from the drone flies above the plot and returns down to the ground (1m + 1m)
-	// each axis -1 is from when the farthest plot (10x10 square meter) of each axis will not be counted (got this logic from the documentation of the test)
-	resp.Distance += (estate.Length-1)*10 + (estate.Width-1)*10 + 2
+	tree.Status = status
+	tree.RemovalReason = &fellReq.Reason
+	tree.RemovedAt = &removedAt
+	tree.Version++
+	setVersionETag(ctx, tree.Version)
+
+	return ctx.JSON(http.StatusOK, treeToResponse(tree, estate))
+}
+
+func (s *Server) ReplantTree(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) error {
+	var replantReq generated.ReplantTreeJSONBody
+	err := ctx.Bind(&replantReq)
+	if err != nil {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body is malformed")
+	}
+
+	err = ctx.Validate(replantReq)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	previousTree, err := s.Repository.GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "tree_not_found", "Tree not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	if previousTree.Status == repository.TreeStatusAlive {
+		return problem(ctx, http.StatusConflict, "tree_still_alive", "Tree is still alive, fell it before replanting")
+	}
+	// The boundary may have been set since the tree was felled, its plot can't be planted anymore
+	if !newPlotMask(estate).contains(previousTree.HorizontalPosition, previousTree.VerticalPosition) {
+		return problem(ctx, http.StatusConflict, "tree_outside_boundary", "Tree is outside of the estate's boundary, its plot can't be replanted")
+	}
+
+	newTree := repository.Tree{
+		EstateID:           previousTree.EstateID,
+		HorizontalPosition: previousTree.HorizontalPosition,
+		VerticalPosition:   previousTree.VerticalPosition,
+		Height:             replantReq.Height,
+		Species:            replantReq.Species,
+		Variety:            replantReq.Variety,
+		HealthStatus:       repository.HealthStatusHealthy,
+		Status:             repository.TreeStatusAlive,
+		PreviousTreeID:     &previousTree.ID,
+	}
+	if replantReq.PlantingDate != nil {
+		newTree.PlantingDate = &replantReq.PlantingDate.Time
+	}
+	if replantReq.HealthStatus != nil {
+		newTree.HealthStatus = string(*replantReq.HealthStatus)
+	}
+
+	err = s.Repository.CreateTree(ctx.Request().Context(), &newTree, plotPlantable)
+	if err != nil {
+		// previous_tree_id is unique, so a tree can only be replanted once
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return problem(ctx, http.StatusConflict, "tree_already_replanted", "Tree has already been replanted")
+		}
+		if errors.Is(err, repository.ErrTreeOutOfEstate) {
+			return problem(ctx, http.StatusConflict, "tree_outside_boundary", "Tree is outside of the estate's boundary, its plot can't be replanted")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.CreateTreeResponse{
+		Id: stringToUUID(newTree.ID),
+	}
+
+	return ctx.JSON(http.StatusCreated, resp)
+}
+
+func (s *Server) CreateTreeHarvest(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) error {
+	var createReq generated.CreateTreeHarvestJSONBody
+	err := ctx.Bind(&createReq)
+	if err != nil {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body is malformed")
+	}
+
+	err = ctx.Validate(createReq)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	_, err = s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	tree, err := s.Repository.GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "tree_not_found", "Tree not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	harvestedOn := time.Now().UTC().Truncate(24 * time.Hour)
+	if createReq.Date != nil {
+		harvestedOn = createReq.Date.Time
+	}
+
+	// Harvests of felled or dead trees can still be recorded for the days they were standing
+	if tree.RemovedAt != nil && harvestedOn.After(*tree.RemovedAt) {
+		return problem(ctx, http.StatusConflict, "tree_removed_before_harvest", "Tree was removed before the harvest date")
+	}
+
+	newHarvest := repository.Harvest{
+		EstateID:    tree.EstateID,
+		TreeID:      tree.ID,
+		HarvestedOn: harvestedOn,
+		BunchCount:  createReq.BunchCount,
+		WeightKg:    createReq.Weight,
+	}
+
+	err = s.Repository.CreateHarvest(ctx.Request().Context(), &newHarvest)
+	if err != nil {
+		// A tree is harvested at most once per harvest round
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return problem(ctx, http.StatusConflict, "tree_already_harvested", "Tree has already been harvested on this date")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	return ctx.JSON(http.StatusCreated, harvestToResponse(newHarvest))
+}
+
+func (s *Server) GetTreeHarvests(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) error {
+	_, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	tree, err := s.Repository.GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "tree_not_found", "Tree not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	harvests, err := s.Repository.GetHarvestsByTreeID(ctx.Request().Context(), tree.ID)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetTreeHarvestsResponse{
+		Harvests: make([]generated.Harvest, 0, len(harvests)),
+	}
+	for _, harvest := range harvests {
+		resp.Harvests = append(resp.Harvests, harvestToResponse(harvest))
+		resp.BunchCount += harvest.BunchCount
+		resp.Weight += harvest.WeightKg
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetEstateTrees(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateTreesParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	page, pageSize := defaultPage, defaultPageSize
+	if params.Page != nil {
+		page = *params.Page
+	}
+	if params.PageSize != nil {
+		pageSize = *params.PageSize
+	}
+
+	filter := repository.TreeFilter{
+		Species:         params.Species,
+		Variety:         params.Variety,
+		IncludeInactive: params.IncludeInactive != nil && *params.IncludeInactive,
+		Limit:           pageSize,
+		Offset:          (page - 1) * pageSize,
+	}
+	if params.HealthStatus != nil {
+		healthStatus := string(*params.HealthStatus)
+		filter.HealthStatus = &healthStatus
+	}
+	if params.BlockId != nil {
+		block, err := s.Repository.GetBlockByID(ctx.Request().Context(), estate.ID, params.BlockId.String())
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return problem(ctx, http.StatusNotFound, "block_not_found", "Block not found")
+			}
+			return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+		}
+		filter.Block = &block
+	}
+
+	trees, total, err := s.Repository.GetTreesByEstateID(ctx.Request().Context(), estate.ID, filter)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetEstateTreesResponse{
+		Trees:    make([]generated.Tree, 0, len(trees)),
+		Page:     page,
+		PageSize: pageSize,
+		Total:    int(total),
+	}
+	for _, tree := range trees {
+		resp.Trees = append(resp.Trees, treeToResponse(tree, estate))
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetNearbyTrees(ctx echo.Context, estateID openapi_types.UUID, params generated.GetNearbyTreesParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	if params.X > estate.Length || params.Y > estate.Width {
+		return problem(ctx, http.StatusBadRequest, "position_out_of_estate", "Position is out of the estate's area")
+	}
+
+	metric := repository.DistanceMetricEuclidean
+	if params.Metric != nil {
+		metric = string(*params.Metric)
+	}
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+
+	trees, err := s.Repository.GetTreesNearPosition(ctx.Request().Context(), estate.ID, params.X, params.Y, params.Radius, metric, includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetNearbyTreesResponse{
+		Trees: make([]generated.NearbyTree, 0, len(trees)),
+	}
+	for _, tree := range trees {
+		resp.Trees = append(resp.Trees, generated.NearbyTree{
+			Tree:     treeToResponse(tree.Tree, estate),
+			Distance: tree.Distance,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetEstateGrid(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateGridParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	cell := defaultGridCell
+	if params.Cell != nil {
+		cell = *params.Cell
+	}
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+
+	cells, err := s.Repository.GetTreeGridByEstateID(ctx.Request().Context(), estate.ID, cell, includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetEstateGridResponse{
+		Cell:    cell,
+		Columns: (estate.Length + cell - 1) / cell,
+		Rows:    (estate.Width + cell - 1) / cell,
+		Cells:   make([]generated.GridCell, 0, len(cells)),
+	}
+	for _, gridCell := range cells {
+		resp.Cells = append(resp.Cells, gridCellToResponse(gridCell, cell, estate))
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetEstateMapPng(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateMapPngParams) error {
+	return s.renderEstateMap(ctx, estateID, params, "image/png", func(m estateMap) ([]byte, error) {
+		return m.renderPNG()
+	})
+}
+
+func (s *Server) GetEstateMapSvg(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateMapSvgParams) error {
+	return s.renderEstateMap(ctx, estateID, generated.GetEstateMapPngParams(params), "image/svg+xml", func(m estateMap) ([]byte, error) {
+		return m.renderSVG(), nil
+	})
+}
+
+// renderEstateMap draws the estate from its tree grid, so big estates are aggregated
+// in the database rather than loading every tree.
+func (s *Server) renderEstateMap(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateMapPngParams, contentType string, render func(m estateMap) ([]byte, error)) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	size := defaultMapSize
+	if params.Size != nil {
+		size = *params.Size
+	}
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+	dronePath := params.DronePath != nil && *params.DronePath
+
+	cell := mapCellSize(estate, size)
+	cells, err := s.Repository.GetTreeGridByEstateID(ctx.Request().Context(), estate.ID, cell, includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	image, err := render(newEstateMap(estate, size, cell, cells, dronePath))
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	return ctx.Blob(http.StatusOK, contentType, image)
+}
+
+func (s *Server) GetEstateYield(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateYieldParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	if params.From != nil && params.To != nil && params.To.Before(params.From.Time) {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "to is before from")
+	}
+	if params.YFrom != nil && params.YTo != nil && *params.YTo < *params.YFrom {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "y_to is less than y_from")
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	filter := repository.YieldFilter{
+		YFrom: params.YFrom,
+		YTo:   params.YTo,
+	}
+	if params.From != nil {
+		filter.From = &params.From.Time
+	}
+	if params.To != nil {
+		filter.To = &params.To.Time
+	}
+
+	totals, err := s.Repository.GetYieldByEstateID(ctx.Request().Context(), estate.ID, "", filter)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	var resp generated.GetEstateYieldResponse
+	if len(totals) > 0 {
+		resp.HarvestCount = totals[0].HarvestCount
+		resp.TreeCount = totals[0].TreeCount
+		resp.BunchCount = totals[0].BunchCount
+		resp.Weight = totals[0].WeightKg
+	}
+
+	if params.GroupBy != nil {
+		yields, err := s.Repository.GetYieldByEstateID(ctx.Request().Context(), estate.ID, string(*params.GroupBy), filter)
+		if err != nil {
+			return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+		}
+
+		groups := make([]generated.YieldGroup, 0, len(yields))
+		for _, yield := range yields {
+			group := generated.YieldGroup{
+				Y:            yield.Y,
+				HarvestCount: yield.HarvestCount,
+				TreeCount:    yield.TreeCount,
+				BunchCount:   yield.BunchCount,
+				Weight:       yield.WeightKg,
+			}
+			if yield.Month != nil {
+				month := yield.Month.Format("2006-01")
+				group.Month = &month
+			}
+			groups = append(groups, group)
+		}
+		resp.Groups = &groups
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetEstateStats(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateStatsParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+	percentiles, bucketSize := statsOptions(params.Percentiles, params.BucketSize)
+	stats, err := s.Repository.GetTreeHeightStatsByEstateID(ctx.Request().Context(), estate.ID, percentileFractions(percentiles), bucketSize, includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := heightStats(stats, percentiles, bucketSize)
+	if params.Breakdown != nil {
+		groups, err := s.Repository.GetTreeHeightBreakdownByEstateID(ctx.Request().Context(), estate.ID, string(*params.Breakdown), includeInactive)
+		if err != nil {
+			return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+		}
+
+		breakdown := make([]generated.TreeGroupStats, 0, len(groups))
+		for _, group := range groups {
+			breakdown = append(breakdown, generated.TreeGroupStats{
+				Species:      group.Species,
+				Variety:      group.Variety,
+				HealthStatus: (*generated.TreeHealthStatus)(group.HealthStatus),
+				Count:        group.Count,
+				Min:          group.MinHeight,
+				Max:          group.MaxHeight,
+				Median:       group.MedianHeight,
+			})
+		}
+		resp.Breakdown = &breakdown
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetEstateStatsHistory(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateStatsHistoryParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	to := snapshotDay(time.Now())
+	if params.To != nil {
+		to = params.To.Time
+	}
+	from := to.AddDate(-1, 0, 0)
+	if params.From != nil {
+		from = params.From.Time
+	}
+	if to.Before(from) {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "to is before from")
+	}
+	interval := generated.GetEstateStatsHistoryParamsIntervalDay
+	if params.Interval != nil {
+		interval = *params.Interval
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	snapshots, err := s.Repository.GetEstateStatSnapshots(ctx.Request().Context(), estate.ID, from, to, string(interval))
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetEstateStatsHistoryResponse{
+		From:      openapi_types.Date{Time: from},
+		To:        openapi_types.Date{Time: to},
+		Interval:  generated.GetEstateStatsHistoryResponseInterval(interval),
+		Snapshots: make([]generated.EstateStatsSnapshot, 0, len(snapshots)),
+	}
+	for _, snapshot := range snapshots {
+		resp.Snapshots = append(resp.Snapshots, generated.EstateStatsSnapshot{
+			Period:      openapi_types.Date{Time: snapshot.Period},
+			Date:        openapi_types.Date{Time: snapshot.SnapshotDate},
+			Count:       snapshot.TreeCount,
+			FelledCount: snapshot.FelledCount,
+			DeadCount:   snapshot.DeadCount,
+			Max:         snapshot.MaxHeight,
+			Min:         snapshot.MinHeight,
+			Median:      snapshot.MedianHeight,
+			Mean:        snapshot.MeanHeight,
+			Stddev:      snapshot.StddevHeight,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetEstateDronePlan(ctx echo.Context, estateId openapi_types.UUID, params generated.GetEstateDronePlanParams) error {
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateId.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+	trees, err := s.Repository.GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estate.ID, includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	var resp generated.GetEstateDronePlanResponse
+	if len(trees) > 0 {
+		var totalTreeHeight int
+		for _, tree := range trees {
+			totalTreeHeight += tree.Height
+		}
+		resp.Distance += totalTreeHeight
+	}
+
+	// 2 is from the drone flies above the plot and returns down to the ground (1m + 1m)
+	resp.Distance += newPlotMask(estate).dronePathDistance() + 2
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetEstateProfile(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateProfileParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	axis := generated.GetEstateProfileParamsAxisRow
+	if params.Axis != nil {
+		axis = *params.Axis
+	}
+
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+	lines, err := s.Repository.GetHeightProfileByEstateID(ctx.Request().Context(), estate.ID, string(axis), includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetEstateProfileResponse{
+		Axis:  generated.GetEstateProfileResponseAxis(axis),
+		Lines: make([]generated.HeightProfileLine, 0, len(lines)),
+	}
+	for _, line := range lines {
+		resp.Lines = append(resp.Lines, generated.HeightProfileLine{
+			Position: line.Position,
+			Count:    line.TreeCount,
+			Max:      line.MaxHeight,
+			Min:      line.MinHeight,
+			Mean:     line.MeanHeight,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CreateDivision(ctx echo.Context, estateID openapi_types.UUID) error {
+	var createReq generated.CreateDivisionJSONBody
+	err := ctx.Bind(&createReq)
+	if err != nil {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body is malformed")
+	}
+
+	err = ctx.Validate(createReq)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	newDivision := repository.Division{
+		EstateID: estate.ID,
+		Name:     createReq.Name,
+	}
+
+	err = s.Repository.CreateDivision(ctx.Request().Context(), &newDivision)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return problem(ctx, http.StatusConflict, "division_name_taken", "A division with this name already exists in the estate")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.CreateDivisionResponse{
+		Id: stringToUUID(newDivision.ID),
+	}
+
+	return ctx.JSON(http.StatusCreated, resp)
+}
+
+func (s *Server) GetDivisions(ctx echo.Context, estateID openapi_types.UUID) error {
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	divisions, err := s.Repository.GetDivisionsByEstateID(ctx.Request().Context(), estate.ID)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetDivisionsResponse{
+		Divisions: make([]generated.Division, 0, len(divisions)),
+	}
+	for _, division := range divisions {
+		resp.Divisions = append(resp.Divisions, generated.Division{
+			Id:        stringToUUID(division.ID),
+			Name:      division.Name,
+			CreatedAt: division.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) CreateBlock(ctx echo.Context, estateID openapi_types.UUID) error {
+	var createReq generated.CreateBlockJSONBody
+	err := ctx.Bind(&createReq)
+	if err != nil {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body is malformed")
+	}
+
+	err = ctx.Validate(createReq)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+	if createReq.XFrom > createReq.XTo || createReq.YFrom > createReq.YTo {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "x_to and y_to must not be less than x_from and y_from")
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	if createReq.XTo > estate.Length || createReq.YTo > estate.Width {
+		return problem(ctx, http.StatusBadRequest, "block_out_of_estate", "Block is out of the estate's area")
+	}
+
+	division, err := s.Repository.GetDivisionByID(ctx.Request().Context(), estate.ID, createReq.DivisionId.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "division_not_found", "Division not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	newBlock := repository.Block{
+		EstateID:   estate.ID,
+		DivisionID: division.ID,
+		Name:       createReq.Name,
+		XFrom:      createReq.XFrom,
+		XTo:        createReq.XTo,
+		YFrom:      createReq.YFrom,
+		YTo:        createReq.YTo,
+	}
+
+	err = s.Repository.CreateBlock(ctx.Request().Context(), &newBlock)
+	if err != nil {
+		// The estate was shrunk since it was read
+		if errors.Is(err, repository.ErrBlockOutOfEstate) {
+			return problem(ctx, http.StatusBadRequest, "block_out_of_estate", "Block is out of the estate's area")
+		}
+		if errors.Is(err, repository.ErrBlockOverlaps) {
+			return problem(ctx, http.StatusConflict, "block_overlap", "Block overlaps another block of the estate")
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return problem(ctx, http.StatusConflict, "block_name_taken", "A block with this name already exists in the estate")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.CreateBlockResponse{
+		Id: stringToUUID(newBlock.ID),
+	}
+
+	return ctx.JSON(http.StatusCreated, resp)
+}
+
+func (s *Server) GetBlocks(ctx echo.Context, estateID openapi_types.UUID, params generated.GetBlocksParams) error {
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	var divisionID *string
+	if params.DivisionId != nil {
+		id := params.DivisionId.String()
+		divisionID = &id
+	}
+
+	blocks, err := s.Repository.GetBlocksByEstateID(ctx.Request().Context(), estate.ID, divisionID)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	resp := generated.GetBlocksResponse{
+		Blocks: make([]generated.Block, 0, len(blocks)),
+	}
+	for _, block := range blocks {
+		resp.Blocks = append(resp.Blocks, blockToResponse(block, estate))
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) DeleteBlock(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) error {
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	err = s.Repository.DeleteBlock(ctx.Request().Context(), estate.ID, blockID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "block_not_found", "Block not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) GetBlockStats(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID, params generated.GetBlockStatsParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	block, err := s.Repository.GetBlockByID(ctx.Request().Context(), estate.ID, blockID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "block_not_found", "Block not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+	percentiles, bucketSize := statsOptions(params.Percentiles, params.BucketSize)
+	stats, err := s.Repository.GetTreeHeightStatsByBlock(ctx.Request().Context(), block, percentileFractions(percentiles), bucketSize, includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	return ctx.JSON(http.StatusOK, heightStats(stats, percentiles, bucketSize))
+}
+
+func (s *Server) GetBlockDronePlan(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID, params generated.GetBlockDronePlanParams) error {
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	block, err := s.Repository.GetBlockByID(ctx.Request().Context(), estate.ID, blockID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "block_not_found", "Block not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+	trees, err := s.Repository.GetTreesByBlockAndPlotsLocations(ctx.Request().Context(), block, includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	var resp generated.GetEstateDronePlanResponse
+	for _, tree := range trees {
+		resp.Distance += tree.Height
+	}
+
+	// 2 is from the drone flies above the first plot of the block and returns down to the ground (1m + 1m)
+	resp.Distance += newPlotMask(estate).within(block).dronePathDistance() + 2
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetEstateVacancies(ctx echo.Context, estateID openapi_types.UUID, params generated.GetEstateVacanciesParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	estate, err := s.Repository.GetEstateByID(ctx.Request().Context(), estateID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return problem(ctx, http.StatusNotFound, "estate_not_found", "Estate not found")
+		}
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	vacant, err := s.Repository.GetVacantRunsByEstateID(ctx.Request().Context(), estate.ID, maskRuns(newPlotMask(estate)))
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	page, pageSize, regionCount := defaultPage, defaultPageSize, defaultRegionCount
+	if params.Page != nil {
+		page = *params.Page
+	}
+	if params.PageSize != nil {
+		pageSize = *params.PageSize
+	}
+	if params.RegionCount != nil {
+		regionCount = *params.RegionCount
+	}
+
+	runs, emptyCount := make([]vacantRun, 0, len(vacant)), 0
+	for _, run := range vacant {
+		runs = append(runs, vacantRun{y: run.Y, plotRun: plotRun{from: run.XFrom, to: run.XTo}})
+		emptyCount += run.XTo - run.XFrom + 1
+	}
+	plotCount := estate.PlotCount
+	plantedCount := plotCount - emptyCount
+
+	resp := generated.GetEstateVacanciesResponse{
+		PlotCount:        plotCount,
+		PlantedPlotCount: plantedCount,
+		EmptyPlotCount:   emptyCount,
+		EmptyRuns:        []generated.EmptyRun{},
+		Page:             page,
+		PageSize:         pageSize,
+		Total:            len(runs),
+	}
+	if plotCount > 0 {
+		resp.Occupancy = float64(plantedCount) / float64(plotCount)
+	}
+
+	offset := (page - 1) * pageSize
+	for i := offset; i < len(runs) && i < offset+pageSize; i++ {
+		resp.EmptyRuns = append(resp.EmptyRuns, generated.EmptyRun{Y: runs[i].y, XFrom: runs[i].from, XTo: runs[i].to})
+	}
+
+	resp.LargestRegions = vacantRegions(runs)
+	if len(resp.LargestRegions) > regionCount {
+		resp.LargestRegions = resp.LargestRegions[:regionCount]
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) GetPortfolioStats(ctx echo.Context, params generated.GetPortfolioStatsParams) error {
+	err := ctx.Validate(params)
+	if err != nil {
+		return validationProblem(ctx, err)
+	}
+
+	page, pageSize, rankBy := defaultPage, defaultPageSize, generated.Density
+	if params.Page != nil {
+		page = *params.Page
+	}
+	if params.PageSize != nil {
+		pageSize = *params.PageSize
+	}
+	if params.RankBy != nil {
+		rankBy = *params.RankBy
+	}
+
+	includeInactive := params.IncludeInactive != nil && *params.IncludeInactive
+	percentiles, bucketSize := statsOptions(params.Percentiles, params.BucketSize)
+	filter := repository.PortfolioFilter{
+		Name:  params.Name,
+		Owner: params.Owner,
+	}
+	estates, stats, err := s.Repository.GetPortfolioStats(ctx.Request().Context(), filter, percentileFractions(percentiles), bucketSize, includeInactive)
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	ranking := portfolioRanking(estates, rankBy)
+	resp := generated.GetPortfolioStatsResponse{
+		EstateCount: len(ranking),
+		TreeCount:   stats.Count,
+		Heights:     heightStats(stats, percentiles, bucketSize),
+		Estates:     []generated.PortfolioEstate{},
+		Page:        page,
+		PageSize:    pageSize,
+		Total:       len(ranking),
+	}
+	for _, estate := range ranking {
+		resp.PlotCount += estate.PlotCount
+	}
+	resp.AreaHectares = float64(resp.PlotCount) / plotsPerHectare
+
+	offset := (page - 1) * pageSize
+	for i := offset; i < len(ranking) && i < offset+pageSize; i++ {
+		resp.Estates = append(resp.Estates, ranking[i])
+	}
 
 	return ctx.JSON(http.StatusOK, resp)
 }
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -15,12 +16,19 @@ import (
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
 	"gorm.io/gorm"
+	"image"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
+func breakdownPtr(breakdown generated.GetEstateStatsParamsBreakdown) *generated.GetEstateStatsParamsBreakdown {
+	return &breakdown
+}
+
 type EndpointsTestSuite struct {
 	suite.Suite
 	repositoryMock *repository.MockRepositoryInterface
@@ -46,7 +54,9 @@ func (e *EndpointsTestSuite) SetupTest() {
 	})
 
 	e.echo = echo.New()
-	e.echo.Validator = &CustomValidator{validator: validator.New()}
+	validate := validator.New()
+	validate.RegisterTagNameFunc(JSONFieldName)
+	e.echo.Validator = &CustomValidator{validator: validate}
 }
 
 func TestEndpointsSuite(t *testing.T) {
@@ -62,11 +72,14 @@ func (e *EndpointsTestSuite) TestCreateEstate() {
 		reqBody string
 	}
 
+	minSize, maxLatitude := "1", "90"
+
 	tests := []struct {
 		name               string
 		args               args
 		fields             fields
 		expectedErr        string
+		expectedViolations []generated.FieldViolation
 		expectedStatusCode int
 	}{
 		{
@@ -77,7 +90,7 @@ func (e *EndpointsTestSuite) TestCreateEstate() {
 			fields: fields{
 				mock: func(ctx echo.Context) {},
 			},
-			expectedErr:        "Invalid input",
+			expectedErr:        "Request body is malformed",
 			expectedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -88,7 +101,10 @@ func (e *EndpointsTestSuite) TestCreateEstate() {
 			fields: fields{
 				mock: func(ctx echo.Context) {},
 			},
-			expectedErr:        "Invalid input",
+			expectedErr: "Some fields are invalid",
+			expectedViolations: []generated.FieldViolation{
+				{Field: "width", Rule: "min", Limit: &minSize},
+			},
 			expectedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -99,8 +115,10 @@ func (e *EndpointsTestSuite) TestCreateEstate() {
 			fields: fields{
 				mock: func(ctx echo.Context) {
 					newEstate := repository.Estate{
-						Length: 20,
-						Width:  10,
+						Length:    20,
+						Width:     10,
+						Bearing:   90,
+						PlotCount: 200,
 					}
 					e.repositoryMock.EXPECT().CreateEstate(ctx.Request().Context(), &newEstate).Return(sql.ErrConnDone)
 				},
@@ -116,8 +134,143 @@ func (e *EndpointsTestSuite) TestCreateEstate() {
 			fields: fields{
 				mock: func(ctx echo.Context) {
 					newEstate := repository.Estate{
-						Length: 20,
-						Width:  10,
+						Length:    20,
+						Width:     10,
+						Bearing:   90,
+						PlotCount: 200,
+					}
+					e.repositoryMock.EXPECT().CreateEstate(ctx.Request().Context(), &newEstate).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name: "Failed, name is longer than 100 characters",
+			args: args{
+				reqBody: fmt.Sprintf(`{"name": "%s", "width": 10, "length": 20}`, strings.Repeat("a", 101)),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success, with name",
+			args: args{
+				reqBody: `{"name": "Sungai Lilin Estate", "width": 10, "length": 20}`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					name := "Sungai Lilin Estate"
+					newEstate := repository.Estate{
+						Name:      &name,
+						Length:    20,
+						Width:     10,
+						Bearing:   90,
+						PlotCount: 200,
+					}
+					e.repositoryMock.EXPECT().CreateEstate(ctx.Request().Context(), &newEstate).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name: "Failed, origin longitude without latitude",
+			args: args{
+				reqBody: `{"origin_longitude": 104.1578, "width": 10, "length": 20}`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "origin_latitude and origin_longitude must be set together",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, origin latitude > 90",
+			args: args{
+				reqBody: `{"origin_latitude": 91, "origin_longitude": 104.1578, "width": 10, "length": 20}`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr: "Some fields are invalid",
+			expectedViolations: []generated.FieldViolation{
+				{Field: "origin_latitude", Rule: "max", Limit: &maxLatitude},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success, with owner and origin",
+			args: args{
+				reqBody: `{"owner": "PT Sawit Pro", "origin_latitude": -2.6154, "origin_longitude": 104.1578, "bearing": 45, "width": 10, "length": 20}`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					owner, latitude, longitude := "PT Sawit Pro", -2.6154, 104.1578
+					newEstate := repository.Estate{
+						Owner:           &owner,
+						Length:          20,
+						Width:           10,
+						OriginLatitude:  &latitude,
+						OriginLongitude: &longitude,
+						Bearing:         45,
+						PlotCount:       200,
+					}
+					e.repositoryMock.EXPECT().CreateEstate(ctx.Request().Context(), &newEstate).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name: "Failed, boundary vertex outside of the estate",
+			args: args{
+				reqBody: `{"width": 10, "length": 20, "boundary": [{"x": 0, "y": 0}, {"x": 21, "y": 0}, {"x": 0, "y": 10}]}`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "Boundary must be a simple polygon inside of the estate leaving a plot to plant",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, boundary crossing itself",
+			args: args{
+				reqBody: `{"width": 10, "length": 20, "boundary": [{"x": 0, "y": 0}, {"x": 20, "y": 10}, {"x": 20, "y": 0}, {"x": 0, "y": 10}]}`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "Boundary must be a simple polygon inside of the estate leaving a plot to plant",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, boundary without any plot inside",
+			args: args{
+				reqBody: `{"width": 10, "length": 20, "boundary": [{"x": 0, "y": 0}, {"x": 20, "y": 0}, {"x": 20, "y": 0.2}]}`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "Boundary must be a simple polygon inside of the estate leaving a plot to plant",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success, with boundary",
+			args: args{
+				reqBody: `{"width": 10, "length": 20, "boundary": [{"x": 0, "y": 0}, {"x": 20, "y": 0}, {"x": 0, "y": 10}]}`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					newEstate := repository.Estate{
+						Length:    20,
+						Width:     10,
+						Bearing:   90,
+						Boundary:  repository.Boundary{{X: 0, Y: 0}, {X: 20, Y: 0}, {X: 0, Y: 10}},
+						PlotCount: 100,
 					}
 					e.repositoryMock.EXPECT().CreateEstate(ctx.Request().Context(), &newEstate).Return(nil)
 				},
@@ -136,15 +289,18 @@ func (e *EndpointsTestSuite) TestCreateEstate() {
 
 			test.fields.mock(ctx)
 
-			err := e.server.CreateEstate(ctx)
+			err := e.server.CreateEstate(ctx, generated.CreateEstateParams{})
 			assert.NoError(e.T(), err)
 
-			var resp generated.InvalidInputErrorResponse
+			var resp generated.Problem
 			err = json.Unmarshal(rec.Body.Bytes(), &resp)
 			assert.NoError(e.T(), err)
 
 			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
-			assert.Equal(e.T(), test.expectedErr, resp.Error)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+			if test.expectedViolations != nil {
+				assert.Equal(e.T(), &test.expectedViolations, resp.Errors)
+			}
 		})
 	}
 }
@@ -175,7 +331,7 @@ func (e *EndpointsTestSuite) TestCreateTree() {
 			fields: fields{
 				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
 			},
-			expectedErr:        "Invalid input",
+			expectedErr:        "Request body is malformed",
 			expectedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -187,7 +343,7 @@ func (e *EndpointsTestSuite) TestCreateTree() {
 			fields: fields{
 				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
 			},
-			expectedErr:        "Invalid input",
+			expectedErr:        "Some fields are invalid",
 			expectedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -236,6 +392,25 @@ func (e *EndpointsTestSuite) TestCreateTree() {
 			expectedErr:        "Tree position is out of the estate's area",
 			expectedStatusCode: http.StatusBadRequest,
 		},
+		{
+			name: "Failed, tree position is out of the estate's boundary",
+			args: args{
+				reqBody:  `{"x": 5, "y": 5, "height": 15}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   5,
+						Width:    5,
+						Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 0, Y: 5}},
+					}, nil)
+				},
+			},
+			expectedErr:        "Tree position is out of the estate's area",
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name: "Failed, got error from CreateTree repo",
 			args: args{
@@ -254,12 +429,40 @@ func (e *EndpointsTestSuite) TestCreateTree() {
 						HorizontalPosition: 1,
 						VerticalPosition:   20,
 						Height:             15,
-					}).Return(sql.ErrConnDone)
+						HealthStatus:       repository.HealthStatusHealthy,
+						Status:             repository.TreeStatusAlive,
+					}, gomock.Any()).Return(sql.ErrConnDone)
 				},
 			},
 			expectedErr:        "Oops, something wrong with the server. Please try again later",
 			expectedStatusCode: http.StatusInternalServerError,
 		},
+		{
+			name: "Failed, estate shrunk since it was read",
+			args: args{
+				reqBody:  `{"x": 1, "y": 20, "height": 15}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 5,
+						Width:  20,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateTree(ctx.Request().Context(), &repository.Tree{
+						EstateID:           estateID.String(),
+						HorizontalPosition: 1,
+						VerticalPosition:   20,
+						Height:             15,
+						HealthStatus:       repository.HealthStatusHealthy,
+						Status:             repository.TreeStatusAlive,
+					}, gomock.Any()).Return(repository.ErrTreeOutOfEstate)
+				},
+			},
+			expectedErr:        "Tree position is out of the estate's area",
+			expectedStatusCode: http.StatusBadRequest,
+		},
 		{
 			name: "Success",
 			args: args{
@@ -278,7 +481,52 @@ func (e *EndpointsTestSuite) TestCreateTree() {
 						HorizontalPosition: 1,
 						VerticalPosition:   20,
 						Height:             15,
-					}).Return(nil)
+						HealthStatus:       repository.HealthStatusHealthy,
+						Status:             repository.TreeStatusAlive,
+					}, gomock.Any()).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name: "Failed, unknown health status",
+			args: args{
+				reqBody:  `{"x": 1, "y": 20, "height": 15, "health_status": "sick"}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Success, with species, variety, planting date and health status",
+			args: args{
+				reqBody:  `{"x": 1, "y": 20, "height": 15, "species": "Elaeis guineensis", "variety": "Tenera", "planting_date": "2019-03-21", "health_status": "diseased"}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					species, variety := "Elaeis guineensis", "Tenera"
+					plantingDate := time.Date(2019, 3, 21, 0, 0, 0, 0, time.UTC)
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 5,
+						Width:  20,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateTree(ctx.Request().Context(), &repository.Tree{
+						EstateID:           estateID.String(),
+						HorizontalPosition: 1,
+						VerticalPosition:   20,
+						Height:             15,
+						Species:            &species,
+						Variety:            &variety,
+						PlantingDate:       &plantingDate,
+						HealthStatus:       repository.HealthStatusDiseased,
+						Status:             repository.TreeStatusAlive,
+					}, gomock.Any()).Return(nil)
 				},
 			},
 			expectedErr:        "",
@@ -296,140 +544,193 @@ func (e *EndpointsTestSuite) TestCreateTree() {
 
 			test.fields.mock(ctx, test.args.estateID)
 
-			err := e.server.CreateTree(ctx, test.args.estateID)
+			err := e.server.CreateTree(ctx, test.args.estateID, generated.CreateTreeParams{})
 			assert.NoError(e.T(), err)
 
-			var resp generated.InvalidInputErrorResponse
+			var resp generated.Problem
 			err = json.Unmarshal(rec.Body.Bytes(), &resp)
 			assert.NoError(e.T(), err)
 
 			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
-			assert.Equal(e.T(), test.expectedErr, resp.Error)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
 		})
 	}
 }
 
-func (e *EndpointsTestSuite) TestGetEstateDronePlan() {
+func (e *EndpointsTestSuite) TestGetEstateStatsHistory() {
 	type fields struct {
 		mock func(ctx echo.Context, estateID openapi_types.UUID)
 	}
 
 	type args struct {
 		estateID openapi_types.UUID
+		params   generated.GetEstateStatsHistoryParams
 	}
 
+	from := openapi_types.Date{Time: time.Date(2024, 05, 01, 0, 0, 0, 0, time.UTC)}
+	to := openapi_types.Date{Time: time.Date(2024, 06, 30, 0, 0, 0, 0, time.UTC)}
+	today := snapshotDay(time.Now())
+	month, unknownInterval := generated.GetEstateStatsHistoryParamsIntervalMonth, generated.GetEstateStatsHistoryParamsInterval("year")
+
 	tests := []struct {
 		name               string
 		args               args
 		fields             fields
 		expectedErr        string
 		expectedStatusCode int
+		expectedResp       generated.GetEstateStatsHistoryResponse
 	}{
 		{
-			name: "Failed, estate not found for GetEstateByID",
+			name: "Failed, unknown interval",
 			args: args{
 				estateID: uuid.New(),
+				params:   generated.GetEstateStatsHistoryParams{Interval: &unknownInterval},
 			},
 			fields: fields{
-				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
-					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
-				},
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
 			},
-			expectedErr:        "Estate not found",
-			expectedStatusCode: http.StatusNotFound,
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
 		},
 		{
-			name: "Failed, got error non record not found for GetEstateByID repo",
+			name: "Failed, to before from",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateStatsHistoryParams{From: &to, To: &from},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "to is before from",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
 			args: args{
 				estateID: uuid.New(),
 			},
 			fields: fields{
 				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
-					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, errors.New("random error"))
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
 				},
 			},
-			expectedErr:        "Oops, something wrong with the server. Please try again later",
-			expectedStatusCode: http.StatusInternalServerError,
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
 		},
 		{
-			name: "Failed, got error for GetTreeHeightsByEstateID repo",
+			name: "Failed, got error from GetEstateStatSnapshots repo",
 			args: args{
 				estateID: uuid.New(),
 			},
 			fields: fields{
 				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
-					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
-						ID: estateID.String(),
-					}, nil)
-					e.repositoryMock.EXPECT().GetTreeHeightsByEstateID(ctx.Request().Context(), estateID.String()).Return([]int(nil), errors.New("random error"))
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetEstateStatSnapshots(ctx.Request().Context(), estateID.String(), today.AddDate(-1, 0, 0), today, repository.StatsIntervalDay).
+						Return([]repository.EstateStatSnapshot(nil), sql.ErrConnDone)
 				},
 			},
 			expectedErr:        "Oops, something wrong with the server. Please try again later",
 			expectedStatusCode: http.StatusInternalServerError,
 		},
 		{
-			name: "Success, with tree count is even",
+			name: "Success, the last year by day by default",
 			args: args{
 				estateID: uuid.New(),
 			},
 			fields: fields{
 				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
-					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
-						ID: estateID.String(),
-					}, nil)
-					e.repositoryMock.EXPECT().GetTreeHeightsByEstateID(ctx.Request().Context(), estateID.String()).Return([]int{1, 2, 3, 4}, nil)
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetEstateStatSnapshots(ctx.Request().Context(), estateID.String(), today.AddDate(-1, 0, 0), today, repository.StatsIntervalDay).
+						Return([]repository.EstateStatSnapshot(nil), nil)
 				},
 			},
 			expectedErr:        "",
 			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateStatsHistoryResponse{
+				From:      openapi_types.Date{Time: today.AddDate(-1, 0, 0)},
+				To:        openapi_types.Date{Time: today},
+				Interval:  generated.GetEstateStatsHistoryResponseIntervalDay,
+				Snapshots: []generated.EstateStatsSnapshot{},
+			},
 		},
 		{
-			name: "Success, with tree count is odd",
+			name: "Success, by month",
 			args: args{
 				estateID: uuid.New(),
+				params:   generated.GetEstateStatsHistoryParams{From: &from, To: &to, Interval: &month},
 			},
 			fields: fields{
 				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
-					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
-						ID: estateID.String(),
-					}, nil)
-					e.repositoryMock.EXPECT().GetTreeHeightsByEstateID(ctx.Request().Context(), estateID.String()).Return([]int{1, 2, 3}, nil)
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetEstateStatSnapshots(ctx.Request().Context(), estateID.String(), from.Time, to.Time, repository.StatsIntervalMonth).
+						Return([]repository.EstateStatSnapshot{
+							{
+								EstateID: estateID.String(), SnapshotDate: from.AddDate(0, 0, 30), Period: from.Time,
+								TreeCount: 2, FelledCount: 1, MinHeight: 4, MaxHeight: 12, MeanHeight: 8, StddevHeight: 4, MedianHeight: 8,
+							},
+							{
+								EstateID: estateID.String(), SnapshotDate: to.Time, Period: from.AddDate(0, 1, 0),
+								TreeCount: 3, FelledCount: 1, DeadCount: 1, MinHeight: 4, MaxHeight: 12, MeanHeight: 8, StddevHeight: 3.25, MedianHeight: 8,
+							},
+						}, nil)
 				},
 			},
 			expectedErr:        "",
 			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateStatsHistoryResponse{
+				From:     from,
+				To:       to,
+				Interval: generated.GetEstateStatsHistoryResponseIntervalMonth,
+				Snapshots: []generated.EstateStatsSnapshot{
+					{
+						Period: from, Date: openapi_types.Date{Time: from.AddDate(0, 0, 30)},
+						Count: 2, FelledCount: 1, Min: 4, Max: 12, Mean: 8, Stddev: 4, Median: 8,
+					},
+					{
+						Period: openapi_types.Date{Time: from.AddDate(0, 1, 0)}, Date: to,
+						Count: 3, FelledCount: 1, DeadCount: 1, Min: 4, Max: 12, Mean: 8, Stddev: 3.25, Median: 8,
+					},
+				},
+			},
 		},
 	}
 
 	for _, test := range tests {
 		e.Suite.Run(test.name, func() {
-			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estates/%s/stats", test.args.estateID), nil)
-			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/stats/history", test.args.estateID), nil)
 			rec := httptest.NewRecorder()
 			ctx := e.echo.NewContext(req, rec)
 
 			test.fields.mock(ctx, test.args.estateID)
 
-			err := e.server.GetEstateStats(ctx, test.args.estateID)
+			err := e.server.GetEstateStatsHistory(ctx, test.args.estateID, test.args.params)
 			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
 
-			var resp generated.InvalidInputErrorResponse
+			if test.expectedStatusCode == http.StatusOK {
+				var resp generated.GetEstateStatsHistoryResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedResp, resp)
+				return
+			}
+
+			var resp generated.Problem
 			err = json.Unmarshal(rec.Body.Bytes(), &resp)
 			assert.NoError(e.T(), err)
-
-			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
-			assert.Equal(e.T(), test.expectedErr, resp.Error)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
 		})
 	}
 }
 
-func (e *EndpointsTestSuite) TestGetEstateStats() {
+func (e *EndpointsTestSuite) TestGetEstateDronePlan() {
 	type fields struct {
 		mock func(ctx echo.Context, estateID openapi_types.UUID)
 	}
 
 	type args struct {
 		estateID openapi_types.UUID
+		params   generated.GetEstateStatsParams
 	}
 
 	tests := []struct {
@@ -466,7 +767,7 @@ func (e *EndpointsTestSuite) TestGetEstateStats() {
 			expectedStatusCode: http.StatusInternalServerError,
 		},
 		{
-			name: "Failed, got error for GetTreesByEstateIDAndPlotsLocations repo",
+			name: "Failed, got error for GetTreeHeightStatsByEstateID repo",
 			args: args{
 				estateID: uuid.New(),
 			},
@@ -475,14 +776,14 @@ func (e *EndpointsTestSuite) TestGetEstateStats() {
 					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
 						ID: estateID.String(),
 					}, nil)
-					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String()).Return([]repository.Tree(nil), errors.New("random error"))
+					e.repositoryMock.EXPECT().GetTreeHeightStatsByEstateID(ctx.Request().Context(), estateID.String(), []float64{0.25, 0.75, 0.9}, 5, false).Return(repository.TreeHeightStats{}, errors.New("random error"))
 				},
 			},
 			expectedErr:        "Oops, something wrong with the server. Please try again later",
 			expectedStatusCode: http.StatusInternalServerError,
 		},
 		{
-			name: "Success, with estate do not have any single tree",
+			name: "Success, with tree count is even",
 			args: args{
 				estateID: uuid.New(),
 			},
@@ -491,14 +792,14 @@ func (e *EndpointsTestSuite) TestGetEstateStats() {
 					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
 						ID: estateID.String(),
 					}, nil)
-					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String()).Return([]repository.Tree(nil), nil)
+					e.repositoryMock.EXPECT().GetTreeHeightStatsByEstateID(ctx.Request().Context(), estateID.String(), []float64{0.25, 0.75, 0.9}, 5, false).Return(repository.TreeHeightStats{Count: 4, MinHeight: 1, MaxHeight: 4, MedianHeight: 2.5}, nil)
 				},
 			},
 			expectedErr:        "",
 			expectedStatusCode: http.StatusOK,
 		},
 		{
-			name: "Success, with estate have tree in its plot",
+			name: "Success, with tree count is odd",
 			args: args{
 				estateID: uuid.New(),
 			},
@@ -507,40 +808,4732 @@ func (e *EndpointsTestSuite) TestGetEstateStats() {
 					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
 						ID: estateID.String(),
 					}, nil)
-					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String()).Return([]repository.Tree{
-						{
-							ID:                 uuid.New().String(),
-							EstateID:           uuid.New().String(),
-							HorizontalPosition: 2,
-							VerticalPosition:   3,
-							Height:             5,
-						},
-					}, nil)
+					e.repositoryMock.EXPECT().GetTreeHeightStatsByEstateID(ctx.Request().Context(), estateID.String(), []float64{0.25, 0.75, 0.9}, 5, false).Return(repository.TreeHeightStats{Count: 3, MinHeight: 1, MaxHeight: 3, MedianHeight: 2}, nil)
 				},
 			},
 			expectedErr:        "",
 			expectedStatusCode: http.StatusOK,
 		},
-	}
-
-	for _, test := range tests {
-		e.Suite.Run(test.name, func() {
-			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estates/%s/drone-plan", test.args.estateID), nil)
-			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-			rec := httptest.NewRecorder()
-			ctx := e.echo.NewContext(req, rec)
-
-			test.fields.mock(ctx, test.args.estateID)
-
-			err := e.server.GetEstateDronePlan(ctx, test.args.estateID)
-			assert.NoError(e.T(), err)
-
-			var resp generated.InvalidInputErrorResponse
-			err = json.Unmarshal(rec.Body.Bytes(), &resp)
-			assert.NoError(e.T(), err)
-
-			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
-			assert.Equal(e.T(), test.expectedErr, resp.Error)
+		{
+			name: "Failed, unknown breakdown",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateStatsParams{Breakdown: breakdownPtr("block")},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, percentile above 100",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateStatsParams{Percentiles: &[]float64{50, 101}},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, got error for GetTreeHeightBreakdownByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateStatsParams{Breakdown: breakdownPtr(generated.Variety)},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID: estateID.String(),
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeHeightStatsByEstateID(ctx.Request().Context(), estateID.String(), []float64{0.25, 0.75, 0.9}, 5, false).Return(repository.TreeHeightStats{Count: 3, MinHeight: 1, MaxHeight: 3, MedianHeight: 2}, nil)
+					e.repositoryMock.EXPECT().GetTreeHeightBreakdownByEstateID(ctx.Request().Context(), estateID.String(), repository.TreeBreakdownVariety, false).Return([]repository.TreeGroupStats(nil), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, with breakdown by variety",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateStatsParams{Breakdown: breakdownPtr(generated.Variety)},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					species, dura, tenera := "Elaeis guineensis", "Dura", "Tenera"
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID: estateID.String(),
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeHeightStatsByEstateID(ctx.Request().Context(), estateID.String(), []float64{0.25, 0.75, 0.9}, 5, false).Return(repository.TreeHeightStats{Count: 3, MinHeight: 1, MaxHeight: 3, MedianHeight: 2}, nil)
+					e.repositoryMock.EXPECT().GetTreeHeightBreakdownByEstateID(ctx.Request().Context(), estateID.String(), repository.TreeBreakdownVariety, false).Return([]repository.TreeGroupStats{
+						{Species: &species, Variety: &dura, Count: 1, MinHeight: 1, MaxHeight: 1, MedianHeight: 1},
+						{Species: &species, Variety: &tenera, Count: 2, MinHeight: 2, MaxHeight: 3, MedianHeight: 2.5},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estates/%s/stats", test.args.estateID), nil)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetEstateStats(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstateStats() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		params   generated.GetEstateDronePlanParams
+	}
+
+	includeInactive := true
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedDistance   int
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error non record not found for GetEstateByID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Failed, got error for GetTreesByEstateIDAndPlotsLocations repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID: estateID.String(),
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String(), false).Return([]repository.Tree(nil), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, with estate do not have any single tree",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID: estateID.String(),
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String(), false).Return([]repository.Tree(nil), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedDistance:   2,
+		},
+		{
+			name: "Success, with estate have tree in its plot",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID: estateID.String(),
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String(), false).Return([]repository.Tree{
+						{
+							ID:                 uuid.New().String(),
+							EstateID:           uuid.New().String(),
+							HorizontalPosition: 2,
+							VerticalPosition:   3,
+							Height:             5,
+						},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedDistance:   7,
+		},
+		{
+			name: "Success, including felled and dead trees",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateDronePlanParams{IncludeInactive: &includeInactive},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID: estateID.String(),
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String(), true).Return([]repository.Tree{
+						{
+							ID:                 uuid.New().String(),
+							EstateID:           uuid.New().String(),
+							HorizontalPosition: 2,
+							VerticalPosition:   3,
+							Height:             5,
+						},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedDistance:   7,
+		},
+		{
+			name: "Success, with estate have a boundary",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   5,
+						Width:    5,
+						Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 0, Y: 5}},
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String(), false).Return([]repository.Tree{
+						{
+							ID:                 uuid.New().String(),
+							HorizontalPosition: 1,
+							VerticalPosition:   1,
+							Height:             5,
+						},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			// 40m along the first row, 40m up to the fifth, 5m over the tree and 2m taking off and landing
+			expectedDistance: 87,
+		},
+		{
+			name: "Success, without a boundary",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 2, Width: 2}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String(), false).Return([]repository.Tree(nil), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedDistance:   22,
+		},
+		{
+			name: "Success, with a boundary covering the whole estate flown over the same",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   2,
+						Width:    2,
+						Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 2}, {X: 0, Y: 2}},
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateIDAndPlotsLocations(ctx.Request().Context(), estateID.String(), false).Return([]repository.Tree(nil), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedDistance:   22,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estates/%s/drone-plan", test.args.estateID), nil)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetEstateDronePlan(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+			if rec.Code == http.StatusOK {
+				var plan generated.GetEstateDronePlanResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &plan)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedDistance, plan.Distance)
+			}
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstateTrees() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		params   generated.GetEstateTreesParams
+	}
+
+	pageSize := 1001
+	page, smallPageSize := 3, 20
+	species := "Elaeis guineensis"
+	healthStatus := generated.TreeHealthStatusDiseased
+	unknownHealthStatus := generated.TreeHealthStatus("sick")
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, page size > 1000",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateTreesParams{PageSize: &pageSize},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, unknown health status",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateTreesParams{HealthStatus: &unknownHealthStatus},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error for GetTreesByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID: estateID.String(),
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateID(ctx.Request().Context(), estateID.String(), repository.TreeFilter{
+						Limit: defaultPageSize,
+					}).Return([]repository.Tree(nil), int64(0), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, with filters and pagination",
+			args: args{
+				estateID: uuid.New(),
+				params: generated.GetEstateTreesParams{
+					Species:      &species,
+					HealthStatus: &healthStatus,
+					Page:         &page,
+					PageSize:     &smallPageSize,
+				},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					diseased := repository.HealthStatusDiseased
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID: estateID.String(),
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesByEstateID(ctx.Request().Context(), estateID.String(), repository.TreeFilter{
+						Species:      &species,
+						HealthStatus: &diseased,
+						Limit:        20,
+						Offset:       40,
+					}).Return([]repository.Tree{
+						{
+							ID:                 uuid.New().String(),
+							EstateID:           estateID.String(),
+							HorizontalPosition: 2,
+							VerticalPosition:   3,
+							Height:             5,
+							Species:            &species,
+							HealthStatus:       repository.HealthStatusDiseased,
+						},
+					}, int64(41), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/trees", test.args.estateID), nil)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetEstateTrees(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetTree() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		treeID   openapi_types.UUID
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedETag       string
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, tree not found for GetTreeByID",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error non record not found for GetTreeByID repo",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{}, errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:                 treeID.String(),
+						EstateID:           estateID.String(),
+						HorizontalPosition: 2,
+						VerticalPosition:   3,
+						Height:             5,
+						HealthStatus:       repository.HealthStatusHealthy,
+						Status:             repository.TreeStatusAlive,
+						Version:            7,
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"7"`,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/tree/%s", test.args.estateID, test.args.treeID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.treeID)
+
+			err := e.server.GetTree(ctx, test.args.estateID, test.args.treeID)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			// The trees have a status of their own, only the problems are decoded
+			if rec.Code >= http.StatusBadRequest {
+				var resp generated.Problem
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedErr, resp.Detail)
+			}
+			assert.Equal(e.T(), test.expectedETag, rec.Header().Get(etagHeader))
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetTreeHistory() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		treeID   openapi_types.UUID
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error for GetTreeLineageByID repo",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeLineageByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return([]repository.Tree(nil), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Failed, tree not found",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeLineageByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return([]repository.Tree(nil), nil)
+				},
+			},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					previousTreeID := uuid.New().String()
+					reason := "Ganoderma basal stem rot"
+					removedAt := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeLineageByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return([]repository.Tree{
+						{
+							ID:                 treeID.String(),
+							EstateID:           estateID.String(),
+							HorizontalPosition: 2,
+							VerticalPosition:   3,
+							Height:             1,
+							HealthStatus:       repository.HealthStatusHealthy,
+							Status:             repository.TreeStatusAlive,
+							PreviousTreeID:     &previousTreeID,
+						},
+						{
+							ID:                 previousTreeID,
+							EstateID:           estateID.String(),
+							HorizontalPosition: 2,
+							VerticalPosition:   3,
+							Height:             12,
+							HealthStatus:       repository.HealthStatusDiseased,
+							Status:             repository.TreeStatusFelled,
+							RemovalReason:      &reason,
+							RemovedAt:          &removedAt,
+						},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/tree/%s/history", test.args.estateID, test.args.treeID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.treeID)
+
+			err := e.server.GetTreeHistory(ctx, test.args.estateID, test.args.treeID)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestFellTree() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID)
+	}
+
+	type args struct {
+		reqBody  string
+		estateID openapi_types.UUID
+		treeID   openapi_types.UUID
+		ifMatch  string
+	}
+
+	removedAt := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedETag       string
+	}{
+		{
+			name: "Failed, reason is missing",
+			args: args{
+				reqBody:  `{"status": "dead"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, unknown status",
+			args: args{
+				reqBody:  `{"status": "alive", "reason": "Mistake"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, without If-Match",
+			args: args{
+				reqBody:  `{"reason": "Ganoderma basal stem rot"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {},
+			},
+			expectedErr:        "If-Match header is required, send the ETag the tree was last read at",
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name: "Failed, tree not found for GetTreeByID",
+			args: args{
+				reqBody:  `{"reason": "Ganoderma basal stem rot"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, tree was changed since it was read",
+			args: args{
+				reqBody:  `{"reason": "Ganoderma basal stem rot"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"1"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:      treeID.String(),
+						Status:  repository.TreeStatusAlive,
+						Version: 2,
+					}, nil)
+				},
+			},
+			expectedErr:        "Tree was changed since it was read",
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Failed, tree got changed concurrently",
+			args: args{
+				reqBody:  `{"reason": "Ganoderma basal stem rot", "date": "2024-05-02"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:      treeID.String(),
+						Status:  repository.TreeStatusAlive,
+						Version: 2,
+					}, nil)
+					e.repositoryMock.EXPECT().FellTree(ctx.Request().Context(), treeID.String(), 2, repository.TreeStatusFelled, "Ganoderma basal stem rot", removedAt).Return(repository.ErrVersionMismatch)
+				},
+			},
+			expectedErr:        "Tree was changed since it was read",
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Failed, tree is already felled",
+			args: args{
+				reqBody:  `{"reason": "Ganoderma basal stem rot"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:      treeID.String(),
+						Status:  repository.TreeStatusFelled,
+						Version: 2,
+					}, nil)
+				},
+			},
+			expectedErr:        "Tree is already felled or dead",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Failed, tree got felled concurrently",
+			args: args{
+				reqBody:  `{"reason": "Ganoderma basal stem rot", "date": "2024-05-02"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:      treeID.String(),
+						Status:  repository.TreeStatusAlive,
+						Version: 2,
+					}, nil)
+					e.repositoryMock.EXPECT().FellTree(ctx.Request().Context(), treeID.String(), 2, repository.TreeStatusFelled, "Ganoderma basal stem rot", removedAt).Return(repository.ErrTreeNotAlive)
+				},
+			},
+			expectedErr:        "Tree is already felled or dead",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Failed, got error from FellTree repo",
+			args: args{
+				reqBody:  `{"reason": "Ganoderma basal stem rot", "date": "2024-05-02"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:      treeID.String(),
+						Status:  repository.TreeStatusAlive,
+						Version: 2,
+					}, nil)
+					e.repositoryMock.EXPECT().FellTree(ctx.Request().Context(), treeID.String(), 2, repository.TreeStatusFelled, "Ganoderma basal stem rot", removedAt).Return(sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, dead tree found today",
+			args: args{
+				reqBody:  `{"status": "dead", "reason": "Lightning strike"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:      treeID.String(),
+						Status:  repository.TreeStatusAlive,
+						Version: 2,
+					}, nil)
+					e.repositoryMock.EXPECT().FellTree(ctx.Request().Context(), treeID.String(), 2, repository.TreeStatusDead, "Lightning strike", gomock.Any()).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+		},
+		{
+			name: "Success, any version matched",
+			args: args{
+				reqBody:  `{"reason": "Ganoderma basal stem rot", "date": "2024-05-02"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+				ifMatch:  "*",
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:      treeID.String(),
+						Status:  repository.TreeStatusAlive,
+						Version: 2,
+					}, nil)
+					e.repositoryMock.EXPECT().FellTree(ctx.Request().Context(), treeID.String(), 2, repository.TreeStatusFelled, "Ganoderma basal stem rot", removedAt).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/estate/%s/tree/%s/fell", test.args.estateID, test.args.treeID), strings.NewReader(test.args.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.treeID)
+
+			params := generated.FellTreeParams{}
+			if test.args.ifMatch != "" {
+				params.IfMatch = &test.args.ifMatch
+			}
+
+			err := e.server.FellTree(ctx, test.args.estateID, test.args.treeID, params)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			// The trees have a status of their own, only the problems are decoded
+			if rec.Code >= http.StatusBadRequest {
+				var resp generated.Problem
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedErr, resp.Detail)
+			}
+			assert.Equal(e.T(), test.expectedETag, rec.Header().Get(etagHeader))
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestReplantTree() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID)
+	}
+
+	type args struct {
+		reqBody  string
+		estateID openapi_types.UUID
+		treeID   openapi_types.UUID
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, height > 30",
+			args: args{
+				reqBody:  `{"height": 31}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				reqBody:  `{"height": 1}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, tree is still alive",
+			args: args{
+				reqBody:  `{"height": 1}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:     treeID.String(),
+						Status: repository.TreeStatusAlive,
+					}, nil)
+				},
+			},
+			expectedErr:        "Tree is still alive, fell it before replanting",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Failed, tree is outside of the boundary set since it was felled",
+			args: args{
+				reqBody:  `{"height": 1}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   10,
+						Width:    10,
+						Boundary: repository.Boundary{{X: 5, Y: 5}, {X: 10, Y: 5}, {X: 10, Y: 10}},
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:                 treeID.String(),
+						EstateID:           estateID.String(),
+						HorizontalPosition: 2,
+						VerticalPosition:   3,
+						Status:             repository.TreeStatusFelled,
+					}, nil)
+				},
+			},
+			expectedErr:        "Tree is outside of the estate's boundary, its plot can't be replanted",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Failed, tree has already been replanted",
+			args: args{
+				reqBody:  `{"height": 1}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					previousTreeID := treeID.String()
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 10}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:                 treeID.String(),
+						EstateID:           estateID.String(),
+						HorizontalPosition: 2,
+						VerticalPosition:   3,
+						Status:             repository.TreeStatusDead,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateTree(ctx.Request().Context(), &repository.Tree{
+						EstateID:           estateID.String(),
+						HorizontalPosition: 2,
+						VerticalPosition:   3,
+						Height:             1,
+						HealthStatus:       repository.HealthStatusHealthy,
+						Status:             repository.TreeStatusAlive,
+						PreviousTreeID:     &previousTreeID,
+					}, gomock.Any()).Return(gorm.ErrDuplicatedKey)
+				},
+			},
+			expectedErr:        "Tree has already been replanted",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Failed, boundary changed since the estate was read",
+			args: args{
+				reqBody:  `{"height": 1}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					previousTreeID := treeID.String()
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 10}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:                 treeID.String(),
+						EstateID:           estateID.String(),
+						HorizontalPosition: 2,
+						VerticalPosition:   3,
+						Status:             repository.TreeStatusDead,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateTree(ctx.Request().Context(), &repository.Tree{
+						EstateID:           estateID.String(),
+						HorizontalPosition: 2,
+						VerticalPosition:   3,
+						Height:             1,
+						HealthStatus:       repository.HealthStatusHealthy,
+						Status:             repository.TreeStatusAlive,
+						PreviousTreeID:     &previousTreeID,
+					}, gomock.Any()).Return(repository.ErrTreeOutOfEstate)
+				},
+			},
+			expectedErr:        "Tree is outside of the estate's boundary, its plot can't be replanted",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Success",
+			args: args{
+				reqBody:  `{"height": 1, "variety": "Tenera", "planting_date": "2024-06-01"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					previousTreeID := treeID.String()
+					variety := "Tenera"
+					plantingDate := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 10}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:                 treeID.String(),
+						EstateID:           estateID.String(),
+						HorizontalPosition: 2,
+						VerticalPosition:   3,
+						Status:             repository.TreeStatusFelled,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateTree(ctx.Request().Context(), &repository.Tree{
+						EstateID:           estateID.String(),
+						HorizontalPosition: 2,
+						VerticalPosition:   3,
+						Height:             1,
+						Variety:            &variety,
+						PlantingDate:       &plantingDate,
+						HealthStatus:       repository.HealthStatusHealthy,
+						Status:             repository.TreeStatusAlive,
+						PreviousTreeID:     &previousTreeID,
+					}, gomock.Any()).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/estate/%s/tree/%s/replant", test.args.estateID, test.args.treeID), strings.NewReader(test.args.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.treeID)
+
+			err := e.server.ReplantTree(ctx, test.args.estateID, test.args.treeID)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetNearbyTrees() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		params   generated.GetNearbyTreesParams
+	}
+
+	chebyshev := generated.Chebyshev
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, radius > 100",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetNearbyTreesParams{X: 1, Y: 1, Radius: 101},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetNearbyTreesParams{X: 1, Y: 1, Radius: 2},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, position is out of the estate's area",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetNearbyTreesParams{X: 6, Y: 1, Radius: 2},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 5,
+						Width:  5,
+					}, nil)
+				},
+			},
+			expectedErr:        "Position is out of the estate's area",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, got error for GetTreesNearPosition repo",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetNearbyTreesParams{X: 3, Y: 3, Radius: 2},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 5,
+						Width:  5,
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesNearPosition(ctx.Request().Context(), estateID.String(), 3, 3, 2, repository.DistanceMetricEuclidean, false).
+						Return([]repository.NearbyTree(nil), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, with chebyshev metric",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetNearbyTreesParams{X: 3, Y: 3, Radius: 1, Metric: &chebyshev},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 5,
+						Width:  5,
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreesNearPosition(ctx.Request().Context(), estateID.String(), 3, 3, 1, repository.DistanceMetricChebyshev, false).
+						Return([]repository.NearbyTree{
+							{
+								Tree: repository.Tree{
+									ID:                 uuid.New().String(),
+									EstateID:           estateID.String(),
+									HorizontalPosition: 4,
+									VerticalPosition:   4,
+									Height:             5,
+									HealthStatus:       repository.HealthStatusPestInfested,
+									Status:             repository.TreeStatusAlive,
+								},
+								Distance: 1,
+							},
+						}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/trees/nearby", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetNearbyTrees(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstateGrid() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		params   generated.GetEstateGridParams
+	}
+
+	invalidCell, cell := 0, 10
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedOccupancy  []float64
+	}{
+		{
+			name: "Failed, cell < 1",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateGridParams{Cell: &invalidCell},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error for GetTreeGridByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 120,
+						Width:  80,
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeGridByEstateID(ctx.Request().Context(), estateID.String(), defaultGridCell, false).
+						Return([]repository.GridCell(nil), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateGridParams{Cell: &cell},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 25,
+						Width:  10,
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeGridByEstateID(ctx.Request().Context(), estateID.String(), 10, false).
+						Return([]repository.GridCell{
+							{CellX: 0, CellY: 0, TreeCount: 50, PlantedPlots: 50, AvgHeight: 12.5, MaxHeight: 20},
+							{CellX: 2, CellY: 0, TreeCount: 25, PlantedPlots: 25, AvgHeight: 7, MaxHeight: 9},
+						}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedOccupancy:  []float64{0.5, 0.5},
+		},
+		{
+			name: "Success, occupancy over the plots of the cell inside of the boundary",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateGridParams{Cell: &cell},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   10,
+						Width:    5,
+						Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 5}},
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeGridByEstateID(ctx.Request().Context(), estateID.String(), 10, false).
+						Return([]repository.GridCell{
+							{CellX: 0, CellY: 0, TreeCount: 20, PlantedPlots: 20, AvgHeight: 12.5, MaxHeight: 20},
+						}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedOccupancy:  []float64{0.8},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/grid", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetEstateGrid(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+
+			if test.expectedOccupancy != nil {
+				var grid generated.GetEstateGridResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &grid)
+				assert.NoError(e.T(), err)
+
+				occupancy := make([]float64, 0, len(grid.Cells))
+				for _, cell := range grid.Cells {
+					occupancy = append(occupancy, cell.Occupancy)
+				}
+				assert.Equal(e.T(), test.expectedOccupancy, occupancy)
+			}
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstateMap() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		format   string
+		params   generated.GetEstateMapPngParams
+	}
+
+	invalidSize, size := 10, 100
+	dronePath := true
+
+	tests := []struct {
+		name                string
+		args                args
+		fields              fields
+		expectedErr         string
+		expectedStatusCode  int
+		expectedContentType string
+		expectedSVG         string
+	}{
+		{
+			name: "Failed, size < 50",
+			args: args{
+				estateID: uuid.New(),
+				format:   "png",
+				params:   generated.GetEstateMapPngParams{Size: &invalidSize},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:         "Some fields are invalid",
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: problemContentType,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+				format:   "svg",
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:         "Estate not found",
+			expectedStatusCode:  http.StatusNotFound,
+			expectedContentType: problemContentType,
+		},
+		{
+			name: "Failed, got error for GetTreeGridByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+				format:   "png",
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 10,
+						Width:  5,
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeGridByEstateID(ctx.Request().Context(), estateID.String(), 1, false).
+						Return([]repository.GridCell(nil), errors.New("random error"))
+				},
+			},
+			expectedErr:         "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode:  http.StatusInternalServerError,
+			expectedContentType: problemContentType,
+		},
+		{
+			name: "Success, png of a big estate rendered in cells of plots",
+			args: args{
+				estateID: uuid.New(),
+				format:   "png",
+				params:   generated.GetEstateMapPngParams{Size: &size, DronePath: &dronePath},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 50000,
+						Width:  20000,
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeGridByEstateID(ctx.Request().Context(), estateID.String(), 500, false).
+						Return([]repository.GridCell{
+							{CellX: 0, CellY: 0, TreeCount: 250000, PlantedPlots: 250000, AvgHeight: 12.5, MaxHeight: 30},
+							{CellX: 99, CellY: 39, TreeCount: 3, PlantedPlots: 3, AvgHeight: 1, MaxHeight: 1},
+						}, nil)
+				},
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/png",
+		},
+		{
+			name: "Success, svg with drone path",
+			args: args{
+				estateID: uuid.New(),
+				format:   "svg",
+				params:   generated.GetEstateMapPngParams{DronePath: &dronePath},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 5,
+						Width:  1,
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeGridByEstateID(ctx.Request().Context(), estateID.String(), 1, false).
+						Return([]repository.GridCell{
+							{CellX: 1, CellY: 0, TreeCount: 1, PlantedPlots: 1, AvgHeight: 10, MaxHeight: 10},
+						}, nil)
+				},
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/svg+xml",
+			expectedSVG:         `<polyline points="80,80 720,80"`,
+		},
+		{
+			name: "Success, svg with drone path over the rows inside of the boundary only",
+			args: args{
+				estateID: uuid.New(),
+				format:   "svg",
+				params:   generated.GetEstateMapPngParams{DronePath: &dronePath},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					// The second row is crossed by a neck too narrow for the centre of any of its plots
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:     estateID.String(),
+						Length: 3,
+						Width:  3,
+						Boundary: repository.Boundary{
+							{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 1}, {X: 0.9, Y: 1}, {X: 0.9, Y: 2}, {X: 3, Y: 2},
+							{X: 3, Y: 3}, {X: 0, Y: 3}, {X: 0, Y: 2}, {X: 0.6, Y: 2}, {X: 0.6, Y: 1}, {X: 0, Y: 1},
+						},
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeGridByEstateID(ctx.Request().Context(), estateID.String(), 1, false).
+						Return([]repository.GridCell(nil), nil)
+				},
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/svg+xml",
+			// Along the first row, then back along the third one
+			expectedSVG: `<polyline points="133,665 665,665 665,133 133,133"`,
+		},
+		{
+			name: "Success, svg with the cell outside of the boundary masked",
+			args: args{
+				estateID: uuid.New(),
+				format:   "svg",
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   2,
+						Width:    2,
+						Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 2}},
+					}, nil)
+					e.repositoryMock.EXPECT().GetTreeGridByEstateID(ctx.Request().Context(), estateID.String(), 1, false).
+						Return([]repository.GridCell(nil), nil)
+				},
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "image/svg+xml",
+			// The plot (2, 2), drawn at the top right, is the only one outside of the boundary
+			expectedSVG: `<rect x="400" y="0" width="400" height="400" fill="#bdbdbd"><title>Outside of the boundary</title></rect></svg>`,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/map.%s", test.args.estateID, test.args.format), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			var err error
+			if test.args.format == "svg" {
+				err = e.server.GetEstateMapSvg(ctx, test.args.estateID, generated.GetEstateMapSvgParams(test.args.params))
+			} else {
+				err = e.server.GetEstateMapPng(ctx, test.args.estateID, test.args.params)
+			}
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.True(e.T(), strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), test.expectedContentType))
+
+			switch test.expectedContentType {
+			case "image/png":
+				img, err := png.Decode(rec.Body)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), image.Rect(0, 0, 100, 40), img.Bounds())
+			case "image/svg+xml":
+				assert.Contains(e.T(), rec.Body.String(), test.expectedSVG)
+			default:
+				var resp generated.Problem
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedErr, resp.Detail)
+			}
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestCreateTreeHarvest() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID)
+	}
+
+	type args struct {
+		reqBody  string
+		estateID openapi_types.UUID
+		treeID   openapi_types.UUID
+	}
+
+	harvestedOn := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	removedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, negative weight",
+			args: args{
+				reqBody:  `{"bunch_count": 3, "weight": -1}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				reqBody:  `{"bunch_count": 3, "weight": 62.5}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, tree not found for GetTreeByID",
+			args: args{
+				reqBody:  `{"bunch_count": 3, "weight": 62.5}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, tree was felled before the harvest date",
+			args: args{
+				reqBody:  `{"bunch_count": 3, "weight": 62.5, "date": "2024-05-02"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:        treeID.String(),
+						EstateID:  estateID.String(),
+						Status:    repository.TreeStatusFelled,
+						RemovedAt: &removedAt,
+					}, nil)
+				},
+			},
+			expectedErr:        "Tree was removed before the harvest date",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Failed, tree has already been harvested on that date",
+			args: args{
+				reqBody:  `{"bunch_count": 3, "weight": 62.5, "date": "2024-05-02"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:       treeID.String(),
+						EstateID: estateID.String(),
+						Status:   repository.TreeStatusAlive,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateHarvest(ctx.Request().Context(), &repository.Harvest{
+						EstateID:    estateID.String(),
+						TreeID:      treeID.String(),
+						HarvestedOn: harvestedOn,
+						BunchCount:  3,
+						WeightKg:    62.5,
+					}).Return(gorm.ErrDuplicatedKey)
+				},
+			},
+			expectedErr:        "Tree has already been harvested on this date",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Failed, got error from CreateHarvest repo",
+			args: args{
+				reqBody:  `{"bunch_count": 3, "weight": 62.5, "date": "2024-05-02"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:       treeID.String(),
+						EstateID: estateID.String(),
+						Status:   repository.TreeStatusAlive,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateHarvest(ctx.Request().Context(), gomock.Any()).Return(sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, harvest of a felled tree recorded on the day it was felled",
+			args: args{
+				reqBody:  `{"bunch_count": 2, "weight": 40, "date": "2024-05-01"}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:        treeID.String(),
+						EstateID:  estateID.String(),
+						Status:    repository.TreeStatusFelled,
+						RemovedAt: &removedAt,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateHarvest(ctx.Request().Context(), &repository.Harvest{
+						EstateID:    estateID.String(),
+						TreeID:      treeID.String(),
+						HarvestedOn: removedAt,
+						BunchCount:  2,
+						WeightKg:    40,
+					}).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name: "Success, harvested today",
+			args: args{
+				reqBody:  `{"bunch_count": 3, "weight": 62.5}`,
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{
+						ID:       treeID.String(),
+						EstateID: estateID.String(),
+						Status:   repository.TreeStatusAlive,
+					}, nil)
+					e.repositoryMock.EXPECT().CreateHarvest(ctx.Request().Context(), gomock.Any()).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/estate/%s/tree/%s/harvests", test.args.estateID, test.args.treeID), strings.NewReader(test.args.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.treeID)
+
+			err := e.server.CreateTreeHarvest(ctx, test.args.estateID, test.args.treeID)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetTreeHarvests() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		treeID   openapi_types.UUID
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.GetTreeHarvestsResponse
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, tree not found for GetTreeByID",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from GetHarvestsByTreeID repo",
+			args: args{
+				estateID: uuid.New(),
+				treeID:   uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{ID: treeID.String()}, nil)
+					e.repositoryMock.EXPECT().GetHarvestsByTreeID(ctx.Request().Context(), treeID.String()).Return([]repository.Harvest(nil), sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, totals of the tree harvests",
+			args: args{
+				estateID: uuid.MustParse("c2dfd742-6a55-41be-b84a-4396f21e2b26"),
+				treeID:   uuid.MustParse("734c8a10-2c10-404b-b41e-ff6e7f1d0a0b"),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, treeID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetTreeByID(ctx.Request().Context(), estateID.String(), treeID.String()).Return(repository.Tree{ID: treeID.String()}, nil)
+					e.repositoryMock.EXPECT().GetHarvestsByTreeID(ctx.Request().Context(), treeID.String()).Return([]repository.Harvest{
+						{ID: "5e0b4d0a-6a0e-4c4b-9a8e-0c1b9e4e8f11", TreeID: treeID.String(), HarvestedOn: time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC), BunchCount: 2, WeightKg: 41.25},
+						{ID: "9b1f3c0e-2d7a-4f5b-8c6d-1e2f3a4b5c6d", TreeID: treeID.String(), HarvestedOn: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), BunchCount: 3, WeightKg: 62.5},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetTreeHarvestsResponse{
+				Harvests: []generated.Harvest{
+					{
+						Id:         uuid.MustParse("5e0b4d0a-6a0e-4c4b-9a8e-0c1b9e4e8f11"),
+						TreeId:     uuid.MustParse("734c8a10-2c10-404b-b41e-ff6e7f1d0a0b"),
+						Date:       openapi_types.Date{Time: time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)},
+						BunchCount: 2,
+						Weight:     41.25,
+					},
+					{
+						Id:         uuid.MustParse("9b1f3c0e-2d7a-4f5b-8c6d-1e2f3a4b5c6d"),
+						TreeId:     uuid.MustParse("734c8a10-2c10-404b-b41e-ff6e7f1d0a0b"),
+						Date:       openapi_types.Date{Time: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+						BunchCount: 3,
+						Weight:     62.5,
+					},
+				},
+				BunchCount: 5,
+				Weight:     103.75,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/tree/%s/harvests", test.args.estateID, test.args.treeID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.treeID)
+
+			err := e.server.GetTreeHarvests(ctx, test.args.estateID, test.args.treeID)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode != http.StatusOK {
+				var resp generated.Problem
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedErr, resp.Detail)
+				return
+			}
+
+			var resp generated.GetTreeHarvestsResponse
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			for i := range resp.Harvests {
+				resp.Harvests[i].CreatedAt = time.Time{}
+			}
+			assert.Equal(e.T(), test.expectedResp, resp)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstateYield() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		params   generated.GetEstateYieldParams
+	}
+
+	periodStart := openapi_types.Date{Time: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}
+	periodEnd := openapi_types.Date{Time: time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)}
+	yFrom, yTo, invalidY := 2, 3, 0
+	april, may := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	aprilMonth, mayMonth := "2024-04", "2024-05"
+	groupByMonth, groupByRow := generated.Month, generated.Row
+	unknownGroupBy := generated.GetEstateYieldParamsGroupBy("species")
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.GetEstateYieldResponse
+	}{
+		{
+			name: "Failed, unknown grouping",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateYieldParams{GroupBy: &unknownGroupBy},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, row out of range",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateYieldParams{YFrom: &invalidY},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, period ends before it starts",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateYieldParams{From: &periodEnd, To: &periodStart},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "to is before from",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, row range ends before it starts",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateYieldParams{YFrom: &yTo, YTo: &yFrom},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "y_to is less than y_from",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error for GetYieldByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetYieldByEstateID(ctx.Request().Context(), estateID.String(), "", repository.YieldFilter{}).Return([]repository.Yield(nil), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Failed, got error for grouped GetYieldByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateYieldParams{GroupBy: &groupByRow},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetYieldByEstateID(ctx.Request().Context(), estateID.String(), "", repository.YieldFilter{}).Return([]repository.Yield{{}}, nil)
+					e.repositoryMock.EXPECT().GetYieldByEstateID(ctx.Request().Context(), estateID.String(), repository.YieldGroupRow, repository.YieldFilter{}).Return([]repository.Yield(nil), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, totals of a row range",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateYieldParams{YFrom: &yFrom, YTo: &yTo},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetYieldByEstateID(ctx.Request().Context(), estateID.String(), "", repository.YieldFilter{YFrom: &yFrom, YTo: &yTo}).
+						Return([]repository.Yield{{HarvestCount: 3, TreeCount: 2, BunchCount: 7, WeightKg: 145.25}}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateYieldResponse{
+				HarvestCount: 3,
+				TreeCount:    2,
+				BunchCount:   7,
+				Weight:       145.25,
+			},
+		},
+		{
+			name: "Success, grouped by month within a period",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateYieldParams{From: &periodStart, To: &periodEnd, GroupBy: &groupByMonth},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					filter := repository.YieldFilter{From: &periodStart.Time, To: &periodEnd.Time}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetYieldByEstateID(ctx.Request().Context(), estateID.String(), "", filter).
+						Return([]repository.Yield{{HarvestCount: 3, TreeCount: 2, BunchCount: 7, WeightKg: 145.25}}, nil)
+					e.repositoryMock.EXPECT().GetYieldByEstateID(ctx.Request().Context(), estateID.String(), repository.YieldGroupMonth, filter).
+						Return([]repository.Yield{
+							{Month: &april, HarvestCount: 1, TreeCount: 1, BunchCount: 2, WeightKg: 41.5},
+							{Month: &may, HarvestCount: 2, TreeCount: 2, BunchCount: 5, WeightKg: 103.75},
+						}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateYieldResponse{
+				HarvestCount: 3,
+				TreeCount:    2,
+				BunchCount:   7,
+				Weight:       145.25,
+				Groups: &[]generated.YieldGroup{
+					{Month: &aprilMonth, HarvestCount: 1, TreeCount: 1, BunchCount: 2, Weight: 41.5},
+					{Month: &mayMonth, HarvestCount: 2, TreeCount: 2, BunchCount: 5, Weight: 103.75},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/yield", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetEstateYield(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode != http.StatusOK {
+				var resp generated.Problem
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedErr, resp.Detail)
+				return
+			}
+
+			var resp generated.GetEstateYieldResponse
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedResp, resp)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstates() {
+	type fields struct {
+		mock func(ctx echo.Context)
+	}
+
+	type args struct {
+		params generated.GetEstatesParams
+	}
+
+	name, minPlotCount, maxPlotCount, invalidPlotCount := "lilin", 10, 100, 0
+	page, pageSize := 2, 10
+	sort, unknownSort := generated.MinusTreeCount, generated.GetEstatesParamsSort("height")
+	createdFrom := openapi_types.Date{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+	createdTo := openapi_types.Date{Time: time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, unknown sort",
+			args: args{
+				params: generated.GetEstatesParams{Sort: &unknownSort},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, min_plot_count < 1",
+			args: args{
+				params: generated.GetEstatesParams{MinPlotCount: &invalidPlotCount},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, plot count range ends before it starts",
+			args: args{
+				params: generated.GetEstatesParams{MinPlotCount: &maxPlotCount, MaxPlotCount: &minPlotCount},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "max_plot_count is less than min_plot_count",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, creation date range ends before it starts",
+			args: args{
+				params: generated.GetEstatesParams{CreatedFrom: &createdTo, CreatedTo: &createdFrom},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "created_to is before created_from",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, got error from GetEstates repo",
+			args: args{
+				params: generated.GetEstatesParams{},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					e.repositoryMock.EXPECT().GetEstates(ctx.Request().Context(), repository.EstateFilter{
+						Sort:  "-created_at",
+						Limit: 100,
+					}).Return([]repository.EstateSummary(nil), int64(0), sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, filtered, sorted and paginated",
+			args: args{
+				params: generated.GetEstatesParams{
+					Name:         &name,
+					MinPlotCount: &minPlotCount,
+					MaxPlotCount: &maxPlotCount,
+					CreatedFrom:  &createdFrom,
+					CreatedTo:    &createdTo,
+					Sort:         &sort,
+					Page:         &page,
+					PageSize:     &pageSize,
+				},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					e.repositoryMock.EXPECT().GetEstates(ctx.Request().Context(), repository.EstateFilter{
+						Name:         &name,
+						MinPlotCount: &minPlotCount,
+						MaxPlotCount: &maxPlotCount,
+						CreatedFrom:  &createdFrom.Time,
+						CreatedTo:    &createdTo.Time,
+						Sort:         "-tree_count",
+						Limit:        10,
+						Offset:       10,
+					}).Return([]repository.EstateSummary{
+						{
+							Estate:    repository.Estate{ID: uuid.NewString(), Length: 10, Width: 5, PlotCount: 50},
+							TreeCount: 42,
+						},
+					}, int64(11), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, "/estate", nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx)
+
+			err := e.server.GetEstates(ctx, test.args.params)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstate() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+	}
+
+	createdAt := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	name := "Sungai Lilin Estate"
+	estateID := uuid.New()
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.Estate
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error for GetPlantedPlotCountByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(0), errors.New("random error"))
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: estateID,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:        estateID.String(),
+						Name:      &name,
+						Length:    500,
+						Width:     200,
+						PlotCount: 100000,
+						Version:   4,
+						CreatedAt: createdAt,
+						UpdatedAt: createdAt,
+					}, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(25000), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.Estate{
+				Id:               estateID,
+				Name:             &name,
+				Length:           500,
+				Width:            200,
+				PlotCount:        100000,
+				AreaHectares:     1000,
+				PlantedPlotCount: 25000,
+				Occupancy:        0.25,
+				CreatedAt:        createdAt,
+				UpdatedAt:        createdAt,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetEstate(ctx, test.args.estateID)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode != http.StatusOK {
+				var resp generated.Problem
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedErr, resp.Detail)
+				return
+			}
+
+			var resp generated.Estate
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedResp, resp)
+			assert.Equal(e.T(), `"4"`, rec.Header().Get(etagHeader))
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestUpdateEstate() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		reqBody  string
+		estateID openapi_types.UUID
+		params   generated.UpdateEstateParams
+	}
+
+	relocate, remove, fell := generated.Relocate, generated.Delete, generated.UpdateEstateParamsForce("fell")
+	noETag, firstETag, secondETag, bothETags := "", `"1"`, `"2"`, `"1", "2"`
+	block := repository.Block{ID: uuid.NewString(), DivisionID: uuid.NewString(), Name: "Block B1", XFrom: 9, XTo: 15, YFrom: 1, YTo: 5}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedTrees      int
+		expectedBlocks     []generated.Block
+		expectedETag       string
+	}{
+		{
+			name: "Failed, unknown force",
+			args: args{
+				reqBody:  `{"length": 10}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{Force: &fell},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, nothing to update",
+			args: args{
+				reqBody:  `{}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "No field to update",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, length > 50000",
+			args: args{
+				reqBody:  `{"length": 50001}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, origin latitude without longitude",
+			args: args{
+				reqBody:  `{"origin_latitude": -2.6154}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "origin_latitude and origin_longitude must be set together",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, bearing is 360",
+			args: args{
+				reqBody:  `{"bearing": 360}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				reqBody:  `{"length": 10}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, trees would be left outside of the estate",
+			args: args{
+				reqBody:  `{"length": 10}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 10, Width: 5, PlotCount: 50}, "", gomock.Any()).Return([]repository.Tree{
+						{ID: uuid.NewString(), HorizontalPosition: 11, VerticalPosition: 1},
+						{ID: uuid.NewString(), HorizontalPosition: 20, VerticalPosition: 5},
+					}, nil, repository.ErrTreesOutOfBounds)
+				},
+			},
+			expectedErr:        "Trees would be left outside of the estate's area",
+			expectedStatusCode: http.StatusConflict,
+			expectedTrees:      2,
+		},
+		{
+			name: "Failed, blocks would be left outside of the estate, even relocating the trees",
+			args: args{
+				reqBody:  `{"length": 10}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{Force: &relocate},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 10, Width: 5, PlotCount: 50}, repository.ResizeForceRelocate, gomock.Any()).
+						Return(nil, []repository.BlockSummary{{Block: block, TreeCount: 7}}, repository.ErrBlocksOutOfBounds)
+				},
+			},
+			expectedErr:        "Blocks would be left outside of the estate's area, delete them first",
+			expectedStatusCode: http.StatusConflict,
+			expectedBlocks: []generated.Block{{
+				Id:         stringToUUID(block.ID),
+				DivisionId: stringToUUID(block.DivisionID),
+				Name:       block.Name,
+				XFrom:      9,
+				XTo:        15,
+				YFrom:      1,
+				YTo:        5,
+				PlotCount:  10,
+				TreeCount:  7,
+			}},
+		},
+		{
+			name: "Failed, estate got deleted while updating",
+			args: args{
+				reqBody:  `{"width": 10}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 20, Width: 10, PlotCount: 200}, "", gomock.Any()).Return([]repository.Tree{}, nil, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, without If-Match",
+			args: args{
+				reqBody:  `{"width": 10}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{IfMatch: &noETag},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "If-Match header is required, send the ETag the estate was last read at",
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name: "Failed, estate was changed since it was read",
+			args: args{
+				reqBody:  `{"width": 10}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{IfMatch: &firstETag},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5, Version: 2}, nil)
+				},
+			},
+			expectedErr:        "Estate was changed since it was read",
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Failed, estate got changed while updating",
+			args: args{
+				reqBody:  `{"width": 10}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{IfMatch: &secondETag},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5, Version: 2}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 20, Width: 10, Version: 2, PlotCount: 200}, "", gomock.Any()).Return([]repository.Tree{}, nil, repository.ErrVersionMismatch)
+				},
+			},
+			expectedErr:        "Estate was changed since it was read",
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Failed, got error from UpdateEstate repo",
+			args: args{
+				reqBody:  `{"width": 10}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 20, Width: 10, PlotCount: 200}, "", gomock.Any()).Return([]repository.Tree(nil), nil, sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, shrunk with the trees outside relocated",
+			args: args{
+				reqBody:  `{"length": 10, "width": 4}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{Force: &relocate},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 10, Width: 4, PlotCount: 40}, repository.ResizeForceRelocate, gomock.Any()).Return([]repository.Tree{
+						{ID: uuid.NewString(), HorizontalPosition: 11, VerticalPosition: 1},
+					}, nil, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(12), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Success, shrunk with the trees outside deleted by felling them",
+			args: args{
+				reqBody:  `{"length": 10, "width": 4}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{Force: &remove},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 10, Width: 4, PlotCount: 40}, repository.ResizeForceFell, gomock.Any()).Return([]repository.Tree{
+						{ID: uuid.NewString(), HorizontalPosition: 11, VerticalPosition: 1},
+					}, nil, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(12), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Success, at the version it was read",
+			args: args{
+				reqBody:  `{"width": 10}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{IfMatch: &bothETags},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5, Version: 2}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 20, Width: 10, Version: 2, PlotCount: 200}, "", gomock.Any()).Return([]repository.Tree{}, nil, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(12), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       `"3"`,
+		},
+		{
+			name: "Success, named and anchored on Earth",
+			args: args{
+				reqBody:  `{"name": "Sungai Lilin Estate", "owner": "PT Sawit Pro", "origin_latitude": -2.6154, "origin_longitude": 104.1578, "bearing": 0}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					name, owner, latitude, longitude := "Sungai Lilin Estate", "PT Sawit Pro", -2.6154, 104.1578
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5, Bearing: 90}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{
+						ID:              estateID.String(),
+						Name:            &name,
+						Owner:           &owner,
+						Length:          20,
+						Width:           5,
+						OriginLatitude:  &latitude,
+						OriginLongitude: &longitude,
+						Bearing:         0,
+						PlotCount:       100,
+					}, "", gomock.Any()).Return([]repository.Tree{}, nil, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(12), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Failed, trees would be left outside of the new boundary",
+			args: args{
+				reqBody:  `{"boundary": [{"x": 0, "y": 0}, {"x": 20, "y": 0}, {"x": 0, "y": 5}]}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					boundary := repository.Boundary{{X: 0, Y: 0}, {X: 20, Y: 0}, {X: 0, Y: 5}}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 5}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 20, Width: 5, Boundary: boundary, PlotCount: 50}, "", gomock.Any()).
+						DoAndReturn(func(_ context.Context, _ repository.Estate, _ string, plantable func(x int, y int) bool) ([]repository.Tree, []repository.BlockSummary, error) {
+							// The trees are checked against the new boundary
+							assert.True(e.T(), plantable(1, 1))
+							assert.False(e.T(), plantable(20, 5))
+							return []repository.Tree{{ID: uuid.NewString(), HorizontalPosition: 20, VerticalPosition: 5}}, nil, repository.ErrTreesOutOfBounds
+						})
+				},
+			},
+			expectedErr:        "Trees would be left outside of the estate's area",
+			expectedStatusCode: http.StatusConflict,
+			expectedTrees:      1,
+		},
+		{
+			name: "Success, boundary removed",
+			args: args{
+				reqBody:  `{"boundary": []}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   20,
+						Width:    5,
+						Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 20, Y: 0}, {X: 0, Y: 5}},
+					}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 20, Width: 5, PlotCount: 100}, "", gomock.Any()).Return([]repository.Tree{}, nil, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(12), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Failed, boundary would be left outside of the shrunk estate",
+			args: args{
+				reqBody:  `{"length": 10}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   20,
+						Width:    5,
+						Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 20, Y: 0}, {X: 0, Y: 5}},
+					}, nil)
+				},
+			},
+			expectedErr:        "Boundary would be left outside of the estate's area, send a new one along with the size",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, no free plot left inside of the boundary to relocate the trees to",
+			args: args{
+				reqBody:  `{"length": 10}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{Force: &relocate},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					boundary := repository.Boundary{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 10}}
+					tree := repository.Tree{ID: uuid.NewString(), HorizontalPosition: 15, VerticalPosition: 8}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   20,
+						Width:    10,
+						Boundary: boundary,
+					}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 10, Width: 10, Boundary: boundary, PlotCount: 55}, repository.ResizeForceRelocate, gomock.Any()).
+						DoAndReturn(func(_ context.Context, _ repository.Estate, _ string, plantable func(x int, y int) bool) ([]repository.Tree, []repository.BlockSummary, error) {
+							// The trees are only relocated onto the plots inside of the boundary
+							assert.True(e.T(), plantable(1, 1))
+							assert.False(e.T(), plantable(10, 10))
+							return []repository.Tree{tree}, nil, repository.ErrNoFreePlots
+						})
+				},
+			},
+			expectedErr:        "Not enough free plots are left inside of the estate to relocate the trees to",
+			expectedStatusCode: http.StatusConflict,
+			expectedTrees:      1,
+		},
+		{
+			name: "Success, shrunk under a boundary with the trees relocated inside of it",
+			args: args{
+				reqBody:  `{"length": 10}`,
+				estateID: uuid.New(),
+				params:   generated.UpdateEstateParams{Force: &relocate},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					boundary := repository.Boundary{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 20, Width: 10, Boundary: boundary}, nil)
+					e.repositoryMock.EXPECT().UpdateEstate(ctx.Request().Context(), repository.Estate{ID: estateID.String(), Length: 10, Width: 10, Boundary: boundary, PlotCount: 100}, repository.ResizeForceRelocate, gomock.Any()).Return([]repository.Tree{
+						{ID: uuid.NewString(), HorizontalPosition: 15, VerticalPosition: 3},
+					}, nil, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(1), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/estate/%s", test.args.estateID), strings.NewReader(test.args.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			// The cases which aren't about the ETag match any version
+			params := test.args.params
+			if params.IfMatch == nil {
+				anyVersion := "*"
+				params.IfMatch = &anyVersion
+			}
+
+			err := e.server.UpdateEstate(ctx, test.args.estateID, params)
+			assert.NoError(e.T(), err)
+
+			var resp generated.TreesOutOfBoundsProblem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+			assert.Len(e.T(), resp.Trees, test.expectedTrees)
+
+			var blocksResp generated.BlocksOutOfBoundsProblem
+			err = json.Unmarshal(rec.Body.Bytes(), &blocksResp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedBlocks, blocksResp.Blocks)
+			if test.expectedETag != "" {
+				assert.Equal(e.T(), test.expectedETag, rec.Header().Get(etagHeader))
+			}
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestDeleteEstate() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		ifMatch  string
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, without If-Match",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "If-Match header is required, send the ETag the estate was last read at",
+			expectedStatusCode: http.StatusPreconditionRequired,
+		},
+		{
+			name: "Failed, estate not found or already deleted",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from GetEstateByID repo",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Failed, estate was changed since it was read",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  `"1"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Version: 2}, nil)
+				},
+			},
+			expectedErr:        "Estate was changed since it was read",
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Failed, weak ETag never matches",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  `W/"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Version: 2}, nil)
+				},
+			},
+			expectedErr:        "Estate was changed since it was read",
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Failed, estate got changed while deleting",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Version: 2}, nil)
+					e.repositoryMock.EXPECT().DeleteEstate(ctx.Request().Context(), estateID.String(), 2).Return(repository.ErrVersionMismatch)
+				},
+			},
+			expectedErr:        "Estate was changed since it was read",
+			expectedStatusCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "Failed, estate got deleted while deleting",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Version: 2}, nil)
+					e.repositoryMock.EXPECT().DeleteEstate(ctx.Request().Context(), estateID.String(), 2).Return(gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from DeleteEstate repo",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Version: 2}, nil)
+					e.repositoryMock.EXPECT().DeleteEstate(ctx.Request().Context(), estateID.String(), 2).Return(sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  `"2"`,
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Version: 2}, nil)
+					e.repositoryMock.EXPECT().DeleteEstate(ctx.Request().Context(), estateID.String(), 2).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name: "Success, any version matched",
+			args: args{
+				estateID: uuid.New(),
+				ifMatch:  "*",
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Version: 2}, nil)
+					e.repositoryMock.EXPECT().DeleteEstate(ctx.Request().Context(), estateID.String(), 2).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/estate/%s", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			params := generated.DeleteEstateParams{}
+			if test.args.ifMatch != "" {
+				params.IfMatch = &test.args.ifMatch
+			}
+
+			err := e.server.DeleteEstate(ctx, test.args.estateID, params)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusNoContent {
+				assert.Empty(e.T(), rec.Body.Bytes())
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestRestoreEstate() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, estate not deleted or past its retention period",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().RestoreEstate(ctx.Request().Context(), estateID.String(), gomock.Any()).Return(gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Deleted estate not found or its retention period is over",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from RestoreEstate repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().RestoreEstate(ctx.Request().Context(), estateID.String(), gomock.Any()).Return(sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().RestoreEstate(ctx.Request().Context(), estateID.String(), gomock.Any()).Return(nil)
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 5}, nil)
+					e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(ctx.Request().Context(), estateID.String()).Return(int64(3), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/estate/%s/restore", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.RestoreEstate(ctx, test.args.estateID)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstateProfile() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		params   generated.GetEstateProfileParams
+	}
+
+	column, unknownAxis := generated.GetEstateProfileParamsAxisColumn, generated.GetEstateProfileParamsAxis("diagonal")
+	includeInactive := true
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.GetEstateProfileResponse
+	}{
+		{
+			name: "Failed, unknown axis",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateProfileParams{Axis: &unknownAxis},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from GetHeightProfileByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetHeightProfileByEstateID(ctx.Request().Context(), estateID.String(), repository.ProfileAxisRow, false).
+						Return([]repository.HeightProfileLine(nil), sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, by row without any tree",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetHeightProfileByEstateID(ctx.Request().Context(), estateID.String(), repository.ProfileAxisRow, false).
+						Return([]repository.HeightProfileLine(nil), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateProfileResponse{
+				Axis:  generated.GetEstateProfileResponseAxisRow,
+				Lines: []generated.HeightProfileLine{},
+			},
+		},
+		{
+			name: "Success, by column including felled and dead trees",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateProfileParams{Axis: &column, IncludeInactive: &includeInactive},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetHeightProfileByEstateID(ctx.Request().Context(), estateID.String(), repository.ProfileAxisColumn, true).
+						Return([]repository.HeightProfileLine{
+							{Position: 1, TreeCount: 2, MinHeight: 4, MaxHeight: 12, MeanHeight: 8},
+							{Position: 3, TreeCount: 1, MinHeight: 20, MaxHeight: 20, MeanHeight: 20},
+						}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateProfileResponse{
+				Axis: generated.GetEstateProfileResponseAxisColumn,
+				Lines: []generated.HeightProfileLine{
+					{Position: 1, Count: 2, Min: 4, Max: 12, Mean: 8},
+					{Position: 3, Count: 1, Min: 20, Max: 20, Mean: 20},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/profile", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetEstateProfile(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusOK {
+				var resp generated.GetEstateProfileResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedResp, resp)
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestCreateDivision() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		reqBody  string
+		estateID openapi_types.UUID
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, name is missing",
+			args: args{
+				reqBody:  `{}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				reqBody:  `{"name": "Division 1"}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, name is already taken",
+			args: args{
+				reqBody:  `{"name": "Division 1"}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().CreateDivision(ctx.Request().Context(), &repository.Division{EstateID: estateID.String(), Name: "Division 1"}).Return(gorm.ErrDuplicatedKey)
+				},
+			},
+			expectedErr:        "A division with this name already exists in the estate",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Success",
+			args: args{
+				reqBody:  `{"name": "Division 1"}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().CreateDivision(ctx.Request().Context(), &repository.Division{EstateID: estateID.String(), Name: "Division 1"}).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/estate/%s/division", test.args.estateID), strings.NewReader(test.args.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.CreateDivision(ctx, test.args.estateID)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetDivisions() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+	}
+
+	divisionID := uuid.New()
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.GetDivisionsResponse
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from GetDivisionsByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetDivisionsByEstateID(ctx.Request().Context(), estateID.String()).Return([]repository.Division(nil), sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetDivisionsByEstateID(ctx.Request().Context(), estateID.String()).Return([]repository.Division{
+						{ID: divisionID.String(), EstateID: estateID.String(), Name: "Division 1"},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetDivisionsResponse{
+				Divisions: []generated.Division{{Id: divisionID, Name: "Division 1"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/divisions", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetDivisions(ctx, test.args.estateID)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusOK {
+				var resp generated.GetDivisionsResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedResp, resp)
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestCreateBlock() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		reqBody  string
+		estateID openapi_types.UUID
+	}
+
+	divisionID := uuid.New()
+	reqBody := fmt.Sprintf(`{"division_id": "%s", "name": "Block A1", "x_from": 1, "x_to": 10, "y_from": 1, "y_to": 5}`, divisionID)
+	newBlock := func(estateID openapi_types.UUID) *repository.Block {
+		return &repository.Block{
+			EstateID:   estateID.String(),
+			DivisionID: divisionID.String(),
+			Name:       "Block A1",
+			XFrom:      1,
+			XTo:        10,
+			YFrom:      1,
+			YTo:        5,
+		}
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, division is missing",
+			args: args{
+				reqBody:  `{"name": "Block A1", "x_from": 1, "x_to": 10, "y_from": 1, "y_to": 5}`,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, x_from > x_to",
+			args: args{
+				reqBody:  fmt.Sprintf(`{"division_id": "%s", "name": "Block A1", "x_from": 11, "x_to": 10, "y_from": 1, "y_to": 5}`, divisionID),
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "x_to and y_to must not be less than x_from and y_from",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				reqBody:  reqBody,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, block is out of the estate's area",
+			args: args{
+				reqBody:  reqBody,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 9, Width: 5}, nil)
+				},
+			},
+			expectedErr:        "Block is out of the estate's area",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, division not found for GetDivisionByID",
+			args: args{
+				reqBody:  reqBody,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 5}, nil)
+					e.repositoryMock.EXPECT().GetDivisionByID(ctx.Request().Context(), estateID.String(), divisionID.String()).Return(repository.Division{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Division not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, estate shrunk since it was read",
+			args: args{
+				reqBody:  reqBody,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 5}, nil)
+					e.repositoryMock.EXPECT().GetDivisionByID(ctx.Request().Context(), estateID.String(), divisionID.String()).Return(repository.Division{ID: divisionID.String()}, nil)
+					e.repositoryMock.EXPECT().CreateBlock(ctx.Request().Context(), newBlock(estateID)).Return(repository.ErrBlockOutOfEstate)
+				},
+			},
+			expectedErr:        "Block is out of the estate's area",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, block overlaps another block",
+			args: args{
+				reqBody:  reqBody,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 5}, nil)
+					e.repositoryMock.EXPECT().GetDivisionByID(ctx.Request().Context(), estateID.String(), divisionID.String()).Return(repository.Division{ID: divisionID.String()}, nil)
+					e.repositoryMock.EXPECT().CreateBlock(ctx.Request().Context(), newBlock(estateID)).Return(repository.ErrBlockOverlaps)
+				},
+			},
+			expectedErr:        "Block overlaps another block of the estate",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Failed, name is already taken",
+			args: args{
+				reqBody:  reqBody,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 5}, nil)
+					e.repositoryMock.EXPECT().GetDivisionByID(ctx.Request().Context(), estateID.String(), divisionID.String()).Return(repository.Division{ID: divisionID.String()}, nil)
+					e.repositoryMock.EXPECT().CreateBlock(ctx.Request().Context(), newBlock(estateID)).Return(gorm.ErrDuplicatedKey)
+				},
+			},
+			expectedErr:        "A block with this name already exists in the estate",
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Success",
+			args: args{
+				reqBody:  reqBody,
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 10, Width: 5}, nil)
+					e.repositoryMock.EXPECT().GetDivisionByID(ctx.Request().Context(), estateID.String(), divisionID.String()).Return(repository.Division{ID: divisionID.String()}, nil)
+					e.repositoryMock.EXPECT().CreateBlock(ctx.Request().Context(), newBlock(estateID)).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusCreated,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/estate/%s/block", test.args.estateID), strings.NewReader(test.args.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.CreateBlock(ctx, test.args.estateID)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetBlocks() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		params   generated.GetBlocksParams
+	}
+
+	blockID, divisionID := uuid.New(), uuid.New()
+	divisionIDStr := divisionID.String()
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.GetBlocksResponse
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from GetBlocksByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetBlocksByEstateID(ctx.Request().Context(), estateID.String(), nil).Return([]repository.BlockSummary(nil), sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, blocks of a division with the plots outside of the boundary left out",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetBlocksParams{DivisionId: &divisionID},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{
+						ID:       estateID.String(),
+						Length:   5,
+						Width:    5,
+						Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 0, Y: 5}},
+					}, nil)
+					e.repositoryMock.EXPECT().GetBlocksByEstateID(ctx.Request().Context(), estateID.String(), &divisionIDStr).Return([]repository.BlockSummary{
+						{
+							Block: repository.Block{
+								ID:         blockID.String(),
+								EstateID:   estateID.String(),
+								DivisionID: divisionID.String(),
+								Name:       "Block A1",
+								XFrom:      1,
+								XTo:        5,
+								YFrom:      1,
+								YTo:        2,
+							},
+							TreeCount: 7,
+						},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetBlocksResponse{
+				Blocks: []generated.Block{
+					{
+						Id:         blockID,
+						DivisionId: divisionID,
+						Name:       "Block A1",
+						XFrom:      1,
+						XTo:        5,
+						YFrom:      1,
+						YTo:        2,
+						PlotCount:  9,
+						TreeCount:  7,
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/blocks", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetBlocks(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusOK {
+				var resp generated.GetBlocksResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedResp, resp)
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestDeleteBlock() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		blockID  openapi_types.UUID
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, block not found",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().DeleteBlock(ctx.Request().Context(), estateID.String(), blockID.String()).Return(gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Block not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().DeleteBlock(ctx.Request().Context(), estateID.String(), blockID.String()).Return(nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/estate/%s/block/%s", test.args.estateID, test.args.blockID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.blockID)
+
+			err := e.server.DeleteBlock(ctx, test.args.estateID, test.args.blockID)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusNoContent {
+				assert.Empty(e.T(), rec.Body.Bytes())
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetBlockStats() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		blockID  openapi_types.UUID
+		params   generated.GetBlockStatsParams
+	}
+
+	includeInactive, bucketSize := true, 10
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.GetEstateStatsResponse
+	}{
+		{
+			name: "Failed, block not found for GetBlockByID",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetBlockByID(ctx.Request().Context(), estateID.String(), blockID.String()).Return(repository.Block{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Block not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from GetTreeHeightStatsByBlock repo",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					block := repository.Block{ID: blockID.String(), EstateID: estateID.String(), XFrom: 1, XTo: 10, YFrom: 1, YTo: 5}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetBlockByID(ctx.Request().Context(), estateID.String(), blockID.String()).Return(block, nil)
+					e.repositoryMock.EXPECT().GetTreeHeightStatsByBlock(ctx.Request().Context(), block, []float64{0.25, 0.75, 0.9}, 5, false).Return(repository.TreeHeightStats{}, sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, including felled and dead trees",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+				params:   generated.GetBlockStatsParams{IncludeInactive: &includeInactive, Percentiles: &[]float64{50}, BucketSize: &bucketSize},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					block := repository.Block{ID: blockID.String(), EstateID: estateID.String(), XFrom: 1, XTo: 10, YFrom: 1, YTo: 5}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String()}, nil)
+					e.repositoryMock.EXPECT().GetBlockByID(ctx.Request().Context(), estateID.String(), blockID.String()).Return(block, nil)
+					e.repositoryMock.EXPECT().GetTreeHeightStatsByBlock(ctx.Request().Context(), block, []float64{0.5}, 10, true).Return(repository.TreeHeightStats{
+						Count:        4,
+						MinHeight:    3,
+						MaxHeight:    20,
+						MeanHeight:   10,
+						StddevHeight: 6.670832032063167,
+						MedianHeight: 8.5,
+						Percentiles:  repository.HeightPercentiles{8.5},
+						Histogram:    []repository.TreeHeightBucket{{Bucket: 0, Count: 2}, {Bucket: 1, Count: 1}, {Bucket: 2, Count: 1}},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateStatsResponse{
+				Count:       4,
+				Min:         3,
+				Max:         20,
+				Median:      8.5,
+				Mean:        10,
+				Stddev:      6.670832032063167,
+				Percentiles: []generated.HeightPercentile{{Percentile: 50, Value: 8.5}},
+				Histogram: generated.HeightHistogram{
+					BucketSize: 10,
+					Buckets: []generated.HeightHistogramBucket{
+						{From: 0, To: 10, Count: 2},
+						{From: 10, To: 20, Count: 1},
+						{From: 20, To: 30, Count: 1},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/block/%s/stats", test.args.estateID, test.args.blockID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.blockID)
+
+			err := e.server.GetBlockStats(ctx, test.args.estateID, test.args.blockID, test.args.params)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusOK {
+				var resp generated.GetEstateStatsResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedResp, resp)
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetBlockDronePlan() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		blockID  openapi_types.UUID
+		params   generated.GetBlockDronePlanParams
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedDistance   int
+	}{
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from GetTreesByBlockAndPlotsLocations repo",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					block := repository.Block{ID: blockID.String(), EstateID: estateID.String(), XFrom: 2, XTo: 4, YFrom: 2, YTo: 3}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 5, Width: 5}, nil)
+					e.repositoryMock.EXPECT().GetBlockByID(ctx.Request().Context(), estateID.String(), blockID.String()).Return(block, nil)
+					e.repositoryMock.EXPECT().GetTreesByBlockAndPlotsLocations(ctx.Request().Context(), block, false).Return([]repository.Tree(nil), sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					block := repository.Block{ID: blockID.String(), EstateID: estateID.String(), XFrom: 2, XTo: 4, YFrom: 2, YTo: 3}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 5, Width: 5}, nil)
+					e.repositoryMock.EXPECT().GetBlockByID(ctx.Request().Context(), estateID.String(), blockID.String()).Return(block, nil)
+					e.repositoryMock.EXPECT().GetTreesByBlockAndPlotsLocations(ctx.Request().Context(), block, false).Return([]repository.Tree{
+						{HorizontalPosition: 3, VerticalPosition: 2, Height: 10},
+					}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			// 20m along the first row, 10m up to the second, 10m over the tree and 2m taking off and landing
+			expectedDistance: 42,
+		},
+		{
+			name: "Success, block covering the whole estate flown over the same as the estate",
+			args: args{
+				estateID: uuid.New(),
+				blockID:  uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID, blockID openapi_types.UUID) {
+					block := repository.Block{ID: blockID.String(), EstateID: estateID.String(), XFrom: 1, XTo: 2, YFrom: 1, YTo: 2}
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 2, Width: 2}, nil)
+					e.repositoryMock.EXPECT().GetBlockByID(ctx.Request().Context(), estateID.String(), blockID.String()).Return(block, nil)
+					e.repositoryMock.EXPECT().GetTreesByBlockAndPlotsLocations(ctx.Request().Context(), block, false).Return([]repository.Tree(nil), nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedDistance:   22,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/block/%s/drone-plan", test.args.estateID, test.args.blockID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID, test.args.blockID)
+
+			err := e.server.GetBlockDronePlan(ctx, test.args.estateID, test.args.blockID, test.args.params)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusOK {
+				var resp generated.GetEstateDronePlanResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedDistance, resp.Distance)
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetEstateVacancies() {
+	type fields struct {
+		mock func(ctx echo.Context, estateID openapi_types.UUID)
+	}
+
+	type args struct {
+		estateID openapi_types.UUID
+		params   generated.GetEstateVacanciesParams
+	}
+
+	pageSize, regionCount := 1, 1
+	// The 3x3 estate is planted on (2, 1), (1, 2) and (2, 2)
+	rows := []repository.PlotRun{{Y: 1, XFrom: 1, XTo: 3}, {Y: 2, XFrom: 1, XTo: 3}, {Y: 3, XFrom: 1, XTo: 3}}
+	vacant := []repository.PlotRun{{Y: 1, XFrom: 1, XTo: 1}, {Y: 1, XFrom: 3, XTo: 3}, {Y: 2, XFrom: 3, XTo: 3}, {Y: 3, XFrom: 1, XTo: 3}}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.GetEstateVacanciesResponse
+	}{
+		{
+			name: "Failed, region count above the maximum",
+			args: args{
+				estateID: uuid.New(),
+				params:   generated.GetEstateVacanciesParams{RegionCount: func() *int { count := 101; return &count }()},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, estate not found for GetEstateByID",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{}, gorm.ErrRecordNotFound)
+				},
+			},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Failed, got error from GetVacantRunsByEstateID repo",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 3, Width: 3, PlotCount: 9}, nil)
+					e.repositoryMock.EXPECT().GetVacantRunsByEstateID(ctx.Request().Context(), estateID.String(), rows).Return([]repository.PlotRun(nil), sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success",
+			args: args{
+				estateID: uuid.New(),
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 3, Width: 3, PlotCount: 9}, nil)
+					e.repositoryMock.EXPECT().GetVacantRunsByEstateID(ctx.Request().Context(), estateID.String(), rows).Return(vacant, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateVacanciesResponse{
+				PlotCount:        9,
+				PlantedPlotCount: 3,
+				EmptyPlotCount:   6,
+				Occupancy:        1.0 / 3,
+				EmptyRuns: []generated.EmptyRun{
+					{Y: 1, XFrom: 1, XTo: 1},
+					{Y: 1, XFrom: 3, XTo: 3},
+					{Y: 2, XFrom: 3, XTo: 3},
+					{Y: 3, XFrom: 1, XTo: 3},
+				},
+				Page:     1,
+				PageSize: 100,
+				Total:    4,
+				LargestRegions: []generated.EmptyRegion{
+					{PlotCount: 5, XFrom: 1, XTo: 3, YFrom: 1, YTo: 3},
+					{PlotCount: 1, XFrom: 1, XTo: 1, YFrom: 1, YTo: 1},
+				},
+			},
+		},
+		{
+			name: "Success, second page of the runs and only the largest region",
+			args: args{
+				estateID: uuid.New(),
+				params: generated.GetEstateVacanciesParams{
+					Page:        func() *int { page := 2; return &page }(),
+					PageSize:    &pageSize,
+					RegionCount: &regionCount,
+				},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context, estateID openapi_types.UUID) {
+					e.repositoryMock.EXPECT().GetEstateByID(ctx.Request().Context(), estateID.String()).Return(repository.Estate{ID: estateID.String(), Length: 3, Width: 3, PlotCount: 9}, nil)
+					e.repositoryMock.EXPECT().GetVacantRunsByEstateID(ctx.Request().Context(), estateID.String(), rows).Return(vacant, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetEstateVacanciesResponse{
+				PlotCount:        9,
+				PlantedPlotCount: 3,
+				EmptyPlotCount:   6,
+				Occupancy:        1.0 / 3,
+				EmptyRuns:        []generated.EmptyRun{{Y: 1, XFrom: 3, XTo: 3}},
+				Page:             2,
+				PageSize:         1,
+				Total:            4,
+				LargestRegions:   []generated.EmptyRegion{{PlotCount: 5, XFrom: 1, XTo: 3, YFrom: 1, YTo: 3}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/estate/%s/vacancies", test.args.estateID), nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx, test.args.estateID)
+
+			err := e.server.GetEstateVacancies(ctx, test.args.estateID, test.args.params)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusOK {
+				var resp generated.GetEstateVacanciesResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedResp, resp)
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestGetPortfolioStats() {
+	type fields struct {
+		mock func(ctx echo.Context)
+	}
+
+	type args struct {
+		params generated.GetPortfolioStatsParams
+	}
+
+	owner, estateName := "PT Sawit Pro", "Sungai Lilin Estate"
+	pageSize, rankBy, unknownRankBy := 1, generated.MedianHeight, generated.GetPortfolioStatsParamsRankBy("height")
+	filter := repository.PortfolioFilter{Owner: &owner}
+	percentiles := []float64{0.25, 0.75, 0.9}
+	firstID, secondID, thirdID := uuid.New(), uuid.New(), uuid.New()
+
+	estates := []repository.PortfolioEstate{
+		{
+			Estate: repository.Estate{ID: firstID.String(), Name: &estateName, Owner: &owner, Length: 10, Width: 5, PlotCount: 50},
+			Stats:  repository.TreeHeightStats{Count: 2, MinHeight: 8, MaxHeight: 8, MedianHeight: 8},
+		},
+		{
+			Estate: repository.Estate{ID: secondID.String(), Owner: &owner, Length: 2, Width: 2, PlotCount: 4},
+			Stats:  repository.TreeHeightStats{Count: 2, MinHeight: 4, MaxHeight: 12, MedianHeight: 8},
+		},
+		{
+			Estate: repository.Estate{ID: thirdID.String(), Owner: &owner, Length: 1, Width: 1, PlotCount: 1},
+		},
+	}
+	stats := repository.TreeHeightStats{
+		Count:        4,
+		MinHeight:    4,
+		MaxHeight:    12,
+		MeanHeight:   8,
+		StddevHeight: 2.8284271247461903,
+		MedianHeight: 8,
+		Percentiles:  repository.HeightPercentiles{7, 9, 10.8},
+		Histogram:    []repository.TreeHeightBucket{{Bucket: 0, Count: 1}, {Bucket: 1, Count: 2}, {Bucket: 2, Count: 1}},
+	}
+	heights := generated.GetEstateStatsResponse{
+		Count:  4,
+		Max:    12,
+		Min:    4,
+		Median: 8,
+		Mean:   8,
+		Stddev: 2.8284271247461903,
+		Percentiles: []generated.HeightPercentile{
+			{Percentile: 25, Value: 7},
+			{Percentile: 75, Value: 9},
+			{Percentile: 90, Value: 10.8},
+		},
+		Histogram: generated.HeightHistogram{
+			BucketSize: 5,
+			Buckets: []generated.HeightHistogramBucket{
+				{From: 0, To: 5, Count: 1},
+				{From: 5, To: 10, Count: 2},
+				{From: 10, To: 15, Count: 1},
+			},
+		},
+	}
+	first := generated.PortfolioEstate{
+		Id: firstID, Name: &estateName, Owner: &owner, PlotCount: 50, AreaHectares: 0.5, TreeCount: 2,
+		Density: 4, MedianHeight: 8, DensityRank: 2, MedianHeightRank: 1,
+	}
+	second := generated.PortfolioEstate{
+		Id: secondID, Owner: &owner, PlotCount: 4, AreaHectares: 0.04, TreeCount: 2,
+		Density: 50, MedianHeight: 8, DensityRank: 1, MedianHeightRank: 1,
+	}
+	third := generated.PortfolioEstate{
+		Id: thirdID, Owner: &owner, PlotCount: 1, AreaHectares: 0.01,
+		DensityRank: 3, MedianHeightRank: 3,
+	}
+
+	tests := []struct {
+		name               string
+		args               args
+		fields             fields
+		expectedErr        string
+		expectedStatusCode int
+		expectedResp       generated.GetPortfolioStatsResponse
+	}{
+		{
+			name: "Failed, unknown ranking",
+			args: args{
+				params: generated.GetPortfolioStatsParams{RankBy: &unknownRankBy},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {},
+			},
+			expectedErr:        "Some fields are invalid",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "Failed, got error from GetPortfolioStats repo",
+			args: args{
+				params: generated.GetPortfolioStatsParams{Owner: &owner},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					e.repositoryMock.EXPECT().GetPortfolioStats(ctx.Request().Context(), filter, percentiles, 5, false).
+						Return([]repository.PortfolioEstate(nil), repository.TreeHeightStats{}, sql.ErrConnDone)
+				},
+			},
+			expectedErr:        "Oops, something wrong with the server. Please try again later",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "Success, without any estate",
+			args: args{
+				params: generated.GetPortfolioStatsParams{Owner: &owner},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					e.repositoryMock.EXPECT().GetPortfolioStats(ctx.Request().Context(), filter, percentiles, 5, false).
+						Return([]repository.PortfolioEstate(nil), repository.TreeHeightStats{}, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetPortfolioStatsResponse{
+				Heights: generated.GetEstateStatsResponse{
+					Percentiles: []generated.HeightPercentile{},
+					Histogram:   generated.HeightHistogram{BucketSize: 5, Buckets: []generated.HeightHistogramBucket{}},
+				},
+				Estates:  []generated.PortfolioEstate{},
+				Page:     1,
+				PageSize: 100,
+			},
+		},
+		{
+			name: "Success, ranked by density",
+			args: args{
+				params: generated.GetPortfolioStatsParams{Owner: &owner},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					e.repositoryMock.EXPECT().GetPortfolioStats(ctx.Request().Context(), filter, percentiles, 5, false).Return(estates, stats, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetPortfolioStatsResponse{
+				EstateCount:  3,
+				PlotCount:    55,
+				AreaHectares: 0.55,
+				TreeCount:    4,
+				Heights:      heights,
+				Estates:      []generated.PortfolioEstate{second, first, third},
+				Page:         1,
+				PageSize:     100,
+				Total:        3,
+			},
+		},
+		{
+			name: "Success, second page ranked by median height, ties keeping their order",
+			args: args{
+				params: generated.GetPortfolioStatsParams{
+					Owner:    &owner,
+					RankBy:   &rankBy,
+					Page:     func() *int { page := 2; return &page }(),
+					PageSize: &pageSize,
+				},
+			},
+			fields: fields{
+				mock: func(ctx echo.Context) {
+					e.repositoryMock.EXPECT().GetPortfolioStats(ctx.Request().Context(), filter, percentiles, 5, false).Return(estates, stats, nil)
+				},
+			},
+			expectedErr:        "",
+			expectedStatusCode: http.StatusOK,
+			expectedResp: generated.GetPortfolioStatsResponse{
+				EstateCount:  3,
+				PlotCount:    55,
+				AreaHectares: 0.55,
+				TreeCount:    4,
+				Heights:      heights,
+				Estates:      []generated.PortfolioEstate{second},
+				Page:         2,
+				PageSize:     1,
+				Total:        3,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(req, rec)
+
+			test.fields.mock(ctx)
+
+			err := e.server.GetPortfolioStats(ctx, test.args.params)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+
+			if test.expectedStatusCode == http.StatusOK {
+				var resp generated.GetPortfolioStatsResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedResp, resp)
+				return
+			}
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+			assert.Equal(e.T(), test.expectedErr, resp.Detail)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestOrganisationIsolation() {
+	type args struct {
+		method  string
+		path    string
+		reqBody string
+		apiKey  string
+	}
+
+	estateA, treeA, blockA := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	estateB := uuid.NewString()
+
+	// The repository is scoped the way the real one is: organisation A owns estateA with its tree,
+	// harvest and block, organisation B owns the empty estateB
+	ownedBy := func(ctx context.Context, organisation string) bool {
+		scoped, _ := repository.OrganisationFromContext(ctx)
+		return scoped == organisation
+	}
+	e.repositoryMock.EXPECT().GetEstateByID(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, estateID string) (repository.Estate, error) {
+		switch {
+		case estateID == estateA && ownedBy(ctx, organisationID):
+			return repository.Estate{ID: estateA, OrganisationID: organisationID, Length: 10, Width: 10, PlotCount: 100, Version: 1}, nil
+		case estateID == estateB && ownedBy(ctx, otherOrganisationID):
+			return repository.Estate{ID: estateB, OrganisationID: otherOrganisationID, Length: 10, Width: 10, PlotCount: 100, Version: 1}, nil
+		}
+		return repository.Estate{}, gorm.ErrRecordNotFound
+	}).AnyTimes()
+	e.repositoryMock.EXPECT().GetTreeByID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, estateID string, treeID string) (repository.Tree, error) {
+		if estateID == estateA && treeID == treeA && ownedBy(ctx, organisationID) {
+			return repository.Tree{ID: treeA, EstateID: estateA, HorizontalPosition: 1, VerticalPosition: 1, Height: 10, Status: repository.TreeStatusAlive, Version: 1}, nil
+		}
+		return repository.Tree{}, gorm.ErrRecordNotFound
+	}).AnyTimes()
+	e.repositoryMock.EXPECT().GetHarvestsByTreeID(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, treeID string) ([]repository.Harvest, error) {
+		if treeID == treeA && ownedBy(ctx, organisationID) {
+			return []repository.Harvest{{ID: uuid.NewString(), TreeID: treeA, EstateID: estateA, WeightKg: 12.5}}, nil
+		}
+		return []repository.Harvest{}, nil
+	}).AnyTimes()
+	e.repositoryMock.EXPECT().GetBlockByID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, estateID string, blockID string) (repository.Block, error) {
+		if estateID == estateA && blockID == blockA && ownedBy(ctx, organisationID) {
+			return repository.Block{ID: blockA, EstateID: estateA, XFrom: 1, XTo: 5, YFrom: 1, YTo: 5}, nil
+		}
+		return repository.Block{}, gorm.ErrRecordNotFound
+	}).AnyTimes()
+	e.repositoryMock.EXPECT().DeleteBlock(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, estateID string, blockID string) error {
+		if estateID == estateA && blockID == blockA && ownedBy(ctx, organisationID) {
+			return nil
+		}
+		return gorm.ErrRecordNotFound
+	}).AnyTimes()
+	e.repositoryMock.EXPECT().GetEstates(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter repository.EstateFilter) ([]repository.EstateSummary, int64, error) {
+		if ownedBy(ctx, organisationID) {
+			return []repository.EstateSummary{{Estate: repository.Estate{ID: estateA, Length: 10, Width: 10, PlotCount: 100}, TreeCount: 1}}, 1, nil
+		}
+		return []repository.EstateSummary{}, 0, nil
+	}).AnyTimes()
+	e.repositoryMock.EXPECT().GetPlantedPlotCountByEstateID(gomock.Any(), gomock.Any()).Return(int64(1), nil).AnyTimes()
+
+	middleware, err := NewAuthMiddleware(AuthConfig{
+		APIKeys: map[string]Principal{
+			"organisation-a-key": {Role: RoleAdmin, OrganisationID: organisationID},
+			"organisation-b-key": {Role: RoleAdmin, OrganisationID: otherOrganisationID},
+		},
+	})
+	assert.NoError(e.T(), err)
+
+	router := echo.New()
+	router.Validator = e.echo.Validator
+	router.Use(middleware)
+	generated.RegisterHandlers(router, e.server)
+
+	tests := []struct {
+		name               string
+		args               args
+		expectedErr        string
+		expectedStatusCode int
+		expectedTotal      *int
+	}{
+		{
+			name:               "Organisation A reading its estate",
+			args:               args{method: http.MethodGet, path: "/estate/" + estateA, apiKey: "organisation-a-key"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Organisation A reading the harvests of its tree",
+			args:               args{method: http.MethodGet, path: "/estate/" + estateA + "/tree/" + treeA + "/harvests", apiKey: "organisation-a-key"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Organisation B reading the estate of A",
+			args:               args{method: http.MethodGet, path: "/estate/" + estateA, apiKey: "organisation-b-key"},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B updating the estate of A",
+			args:               args{method: http.MethodPatch, path: "/estate/" + estateA, reqBody: `{"length": 20}`, apiKey: "organisation-b-key"},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B deleting the estate of A",
+			args:               args{method: http.MethodDelete, path: "/estate/" + estateA, apiKey: "organisation-b-key"},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B reading the tree of A",
+			args:               args{method: http.MethodGet, path: "/estate/" + estateA + "/tree/" + treeA, apiKey: "organisation-b-key"},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B reading the tree of A through its own estate",
+			args:               args{method: http.MethodGet, path: "/estate/" + estateB + "/tree/" + treeA, apiKey: "organisation-b-key"},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B felling the tree of A through its own estate",
+			args:               args{method: http.MethodPost, path: "/estate/" + estateB + "/tree/" + treeA + "/fell", reqBody: `{"reason": "Diseased"}`, apiKey: "organisation-b-key"},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B reading the harvests of the tree of A",
+			args:               args{method: http.MethodGet, path: "/estate/" + estateA + "/tree/" + treeA + "/harvests", apiKey: "organisation-b-key"},
+			expectedErr:        "Estate not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B reading the harvests of the tree of A through its own estate",
+			args:               args{method: http.MethodGet, path: "/estate/" + estateB + "/tree/" + treeA + "/harvests", apiKey: "organisation-b-key"},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B harvesting the tree of A through its own estate",
+			args:               args{method: http.MethodPost, path: "/estate/" + estateB + "/tree/" + treeA + "/harvests", reqBody: `{"weight_kg": 10, "harvested_on": "2024-05-02"}`, apiKey: "organisation-b-key"},
+			expectedErr:        "Tree not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B reading the stats of the block of A through its own estate",
+			args:               args{method: http.MethodGet, path: "/estate/" + estateB + "/block/" + blockA + "/stats", apiKey: "organisation-b-key"},
+			expectedErr:        "Block not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B deleting the block of A through its own estate",
+			args:               args{method: http.MethodDelete, path: "/estate/" + estateB + "/block/" + blockA, apiKey: "organisation-b-key"},
+			expectedErr:        "Block not found",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Organisation B listing the estates without the one of A",
+			args:               args{method: http.MethodGet, path: "/estate", apiKey: "organisation-b-key"},
+			expectedStatusCode: http.StatusOK,
+			expectedTotal:      func() *int { total := 0; return &total }(),
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			req := httptest.NewRequest(test.args.method, test.args.path, strings.NewReader(test.args.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(apiKeyHeader, test.args.apiKey)
+			req.Header.Set("If-Match", "*")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code, rec.Body.String())
+			if test.expectedStatusCode != http.StatusOK {
+				var resp generated.Problem
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), test.expectedErr, resp.Detail)
+				return
+			}
+			if test.expectedTotal != nil {
+				var resp generated.GetEstatesResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &resp)
+				assert.NoError(e.T(), err)
+				assert.Equal(e.T(), *test.expectedTotal, resp.Total)
+				assert.Empty(e.T(), resp.Estates)
+			}
 		})
 	}
 }
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+	"strconv"
+	"strings"
+)
+
+const etagHeader = "ETag"
+
+// versionETag returns the entity tag of a version of an estate or a tree.
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setVersionETag tags a response with the version of the estate or tree it represents.
+func setVersionETag(ctx echo.Context, version int) {
+	ctx.Response().Header().Set(etagHeader, versionETag(version))
+}
+
+// ifMatch reports whether an If-Match header lists the entity tag of the current version of an
+// estate or a tree, or is "*". Weak entity tags never match, the comparison being strong.
+func ifMatch(header string, version int) bool {
+	etag := versionETag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func (e *EndpointsTestSuite) TestIfMatch() {
+	tests := []struct {
+		name           string
+		header         string
+		version        int
+		expectedResult bool
+	}{
+		{
+			name:           "Current version",
+			header:         `"3"`,
+			version:        3,
+			expectedResult: true,
+		},
+		{
+			name:           "Current version in a list",
+			header:         `"1", "3"`,
+			version:        3,
+			expectedResult: true,
+		},
+		{
+			name:           "Any version",
+			header:         "*",
+			version:        3,
+			expectedResult: true,
+		},
+		{
+			name:           "Previous version",
+			header:         `"2"`,
+			version:        3,
+			expectedResult: false,
+		},
+		{
+			name:           "Weak entity tag",
+			header:         `W/"3"`,
+			version:        3,
+			expectedResult: false,
+		},
+		{
+			name:           "Unquoted version",
+			header:         "3",
+			version:        3,
+			expectedResult: false,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			assert.Equal(e.T(), test.expectedResult, ifMatch(test.header, test.version))
+		})
+	}
+}
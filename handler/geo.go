@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"github.com/SawitProRecruitment/UserService/repository"
+	"math"
+)
+
+const (
+	plotMeters = 10
+	// Mean radius of the WGS84 ellipsoid, a sphere is close enough at the size of an estate
+	earthRadiusMeters = 6371008.8
+	defaultBearing    = 90
+)
+
+// plotPosition returns the WGS84 coordinates of the centre of a plot. The estate origin
+// is the outer corner of the plot (1, 1), the x axis points along the estate bearing and
+// the y axis 90 degrees counterclockwise from it, so a bearing of 90 has x going east
+// and y going north. ok is false when the estate has no origin.
+func plotPosition(estate repository.Estate, x int, y int) (latitude float64, longitude float64, ok bool) {
+	if estate.OriginLatitude == nil || estate.OriginLongitude == nil {
+		return
+	}
+
+	alongX := (float64(x) - 0.5) * plotMeters
+	alongY := (float64(y) - 0.5) * plotMeters
+	bearingX := degreesToRadians(estate.Bearing)
+	bearingY := degreesToRadians(estate.Bearing - 90)
+
+	north := alongX*math.Cos(bearingX) + alongY*math.Cos(bearingY)
+	east := alongX*math.Sin(bearingX) + alongY*math.Sin(bearingY)
+
+	// Destination point given the distance and bearing from the origin
+	lat1 := degreesToRadians(*estate.OriginLatitude)
+	lon1 := degreesToRadians(*estate.OriginLongitude)
+	angular := math.Hypot(north, east) / earthRadiusMeters
+	bearing := math.Atan2(east, north)
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(angular) + math.Cos(lat1)*math.Sin(angular)*math.Cos(bearing))
+	lon2 := lon1 + math.Atan2(math.Sin(bearing)*math.Sin(angular)*math.Cos(lat1), math.Cos(angular)-math.Sin(lat1)*math.Sin(lat2))
+
+	longitude = radiansToDegrees(lon2)
+	// Keep the longitude in [-180, 180) when the estate crosses the antimeridian
+	longitude = math.Mod(longitude+540, 360) - 180
+
+	return radiansToDegrees(lat2), longitude, true
+}
+
+func degreesToRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func radiansToDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func (e *EndpointsTestSuite) TestPlotPosition() {
+	type args struct {
+		estate repository.Estate
+		x      int
+		y      int
+	}
+
+	latitude, longitude := -2.6154, 104.1578
+	// 5 meters along a meridian, in degrees
+	halfPlot := radiansToDegrees(5 / earthRadiusMeters)
+
+	tests := []struct {
+		name              string
+		args              args
+		expectedOk        bool
+		expectedLatitude  float64
+		expectedLongitude float64
+	}{
+		{
+			name: "No origin",
+			args: args{
+				estate: repository.Estate{Length: 10, Width: 10, Bearing: 90},
+				x:      1,
+				y:      1,
+			},
+			expectedOk: false,
+		},
+		{
+			name: "x going east and y going north",
+			args: args{
+				estate: repository.Estate{Length: 10, Width: 10, OriginLatitude: new(float64), OriginLongitude: new(float64), Bearing: 90},
+				x:      1,
+				y:      1,
+			},
+			expectedOk:        true,
+			expectedLatitude:  halfPlot,
+			expectedLongitude: halfPlot,
+		},
+		{
+			name: "x going north and y going west",
+			args: args{
+				estate: repository.Estate{Length: 10, Width: 10, OriginLatitude: new(float64), OriginLongitude: new(float64), Bearing: 0},
+				x:      2,
+				y:      1,
+			},
+			expectedOk:        true,
+			expectedLatitude:  3 * halfPlot,
+			expectedLongitude: -halfPlot,
+		},
+		{
+			name: "Away from the equator the longitude degrees are shorter",
+			args: args{
+				estate: repository.Estate{Length: 10, Width: 10, OriginLatitude: &latitude, OriginLongitude: &longitude, Bearing: 90},
+				x:      1,
+				y:      1,
+			},
+			expectedOk:        true,
+			expectedLatitude:  latitude + halfPlot,
+			expectedLongitude: longitude + halfPlot/0.998958,
+		},
+		{
+			name: "Crossing the antimeridian",
+			args: args{
+				estate: repository.Estate{Length: 10, Width: 10, OriginLatitude: new(float64), OriginLongitude: func() *float64 { l := 179.99999; return &l }(), Bearing: 90},
+				x:      1,
+				y:      1,
+			},
+			expectedOk:        true,
+			expectedLatitude:  halfPlot,
+			expectedLongitude: 179.99999 + halfPlot - 360,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			actualLatitude, actualLongitude, ok := plotPosition(test.args.estate, test.args.x, test.args.y)
+
+			assert.Equal(e.T(), test.expectedOk, ok)
+			assert.InDelta(e.T(), test.expectedLatitude, actualLatitude, 1e-7)
+			assert.InDelta(e.T(), test.expectedLongitude, actualLongitude, 1e-7)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyInProgressText = "A request with this Idempotency-Key is still being processed"
+)
+
+// idempotentOperations returns the operations of the spec which accept an Idempotency-Key,
+// keyed by method and echo route.
+func idempotentOperations(swagger *openapi3.T) map[string]bool {
+	operations := map[string]bool{}
+	for path, item := range swagger.Paths {
+		route := strings.NewReplacer("{", ":", "}", "").Replace(path)
+		for method, operation := range item.Operations() {
+			if operation.Parameters.GetByInAndName(openapi3.ParameterInHeader, idempotencyKeyHeader) != nil {
+				operations[method+" "+route] = true
+			}
+		}
+	}
+
+	return operations
+}
+
+// idempotencyRecorder tees the body of a response into a buffer so that it can be replayed.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// requestHash identifies a request by its method, path, query and body, a key being reusable
+// only for the very same request.
+func requestHash(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, req.Method+" "+req.URL.RequestURI()+"\n")
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// NewIdempotencyMiddleware makes the operations accepting an Idempotency-Key safe to retry: the
+// first request sent with a key is processed and its response saved for ttl, the retries with
+// the same key replaying it rather than processing the request again.
+// A key reused for another request is rejected with a 422, and one still held by a request
+// being processed with a 409. The requests failing with a server error release their key so
+// that they can be retried.
+// It must run after the auth middleware, the keys being scoped to the organisation of the caller.
+func NewIdempotencyMiddleware(repo repository.RepositoryInterface, ttl time.Duration) (echo.MiddlewareFunc, error) {
+	swagger, err := generated.GetSwagger()
+	if err != nil {
+		return nil, err
+	}
+	operations := idempotentOperations(swagger)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			key := req.Header.Get(idempotencyKeyHeader)
+			if key == "" || !operations[req.Method+" "+ctx.Path()] {
+				return next(ctx)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return problem(ctx, http.StatusBadRequest, "invalid_input", "Idempotency-Key is longer than 255 characters")
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return problem(ctx, http.StatusBadRequest, "invalid_input", "Request body could not be read")
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := requestHash(req, body)
+			claimed, err := repo.ClaimIdempotencyKey(req.Context(), &repository.IdempotencyKey{
+				Key:         key,
+				RequestHash: hash,
+				ExpiresAt:   time.Now().Add(ttl),
+			})
+			if err != nil {
+				return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+			}
+
+			if !claimed {
+				return replayIdempotentResponse(ctx, repo, key, hash)
+			}
+
+			// The response is saved, or the key released, even when the client gives up waiting
+			saveCtx := context.WithoutCancel(req.Context())
+			recorder := &idempotencyRecorder{ResponseWriter: ctx.Response().Writer}
+			ctx.Response().Writer = recorder
+
+			completed := false
+			defer func() {
+				if !completed {
+					repo.ReleaseIdempotencyKey(saveCtx, key)
+				}
+			}()
+
+			err = next(ctx)
+			completed = true
+
+			res := ctx.Response()
+			if err != nil || !res.Committed || res.Status >= http.StatusInternalServerError {
+				repo.ReleaseIdempotencyKey(saveCtx, key)
+				return err
+			}
+			err = repo.SaveIdempotencyResponse(saveCtx, key, res.Status, res.Header().Get(echo.HeaderContentType), recorder.body.Bytes())
+			if err != nil {
+				// The response is already sent, the key is released for the retries to process the request again
+				repo.ReleaseIdempotencyKey(saveCtx, key)
+				ctx.Logger().Errorf("failed to save the response to idempotency key %q: %v", key, err)
+			}
+
+			return nil
+		}
+	}, nil
+}
+
+// replayIdempotentResponse answers a request whose key is already held with the response saved
+// for it, when it is the same request and its processing is over.
+func replayIdempotentResponse(ctx echo.Context, repo repository.RepositoryInterface, key string, hash string) error {
+	saved, err := repo.GetIdempotencyKey(ctx.Request().Context(), key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// It expired or was released since it was claimed, the client can retry straight away
+		return problem(ctx, http.StatusConflict, "idempotency_key_in_progress", idempotencyInProgressText)
+	}
+	if err != nil {
+		return problem(ctx, http.StatusInternalServerError, "internal_server_error", "Oops, something wrong with the server. Please try again later")
+	}
+
+	if saved.RequestHash != hash {
+		return problem(ctx, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for another request")
+	}
+	if saved.StatusCode == nil {
+		return problem(ctx, http.StatusConflict, "idempotency_key_in_progress", idempotencyInProgressText)
+	}
+
+	ctx.Response().Header().Set(idempotentReplayedHeader, "true")
+
+	return ctx.Blob(*saved.StatusCode, saved.ContentType, saved.ResponseBody)
+}
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+func (e *EndpointsTestSuite) TestIdempotencyMiddleware() {
+	type fields struct {
+		mock func()
+	}
+
+	type args struct {
+		path    string
+		key     string
+		reqBody string
+		// status is what the handler answers with
+		status int
+	}
+
+	created := 201
+	reqBody := `{"length":10,"width":10}`
+	hash := requestHash(httptest.NewRequest(http.MethodPost, "/estate", nil), []byte(reqBody))
+	claim := func(claimed bool, err error) {
+		e.repositoryMock.EXPECT().ClaimIdempotencyKey(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ any, idempotencyKey *repository.IdempotencyKey) (bool, error) {
+				assert.Equal(e.T(), "retry-key", idempotencyKey.Key)
+				assert.Equal(e.T(), hash, idempotencyKey.RequestHash)
+				assert.Equal(e.T(), 24*time.Hour, time.Until(idempotencyKey.ExpiresAt).Round(time.Hour))
+				return claimed, err
+			})
+	}
+
+	middleware, err := NewIdempotencyMiddleware(e.repositoryMock, 24*time.Hour)
+	assert.NoError(e.T(), err)
+
+	tests := []struct {
+		name                 string
+		args                 args
+		fields               fields
+		expectedStatusCode   int
+		expectedResponseBody string
+		expectedReplayed     string
+	}{
+		{
+			name: "Success, without a key",
+			args: args{path: "/estate", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {}},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":"new"}`,
+		},
+		{
+			name: "Success, the operation doesn't accept a key",
+			args: args{path: "/estate/1/division", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {}},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":"new"}`,
+		},
+		{
+			name: "Failed, the key is too long",
+			args: args{path: "/estate", key: strings.Repeat("k", 256), reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {}},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"code":"invalid_input","detail":"Idempotency-Key is longer than 255 characters","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name: "Failed, got error from ClaimIdempotencyKey repo",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {
+					claim(false, sql.ErrConnDone)
+				}},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: `{"code":"internal_server_error","detail":"Oops, something wrong with the server. Please try again later","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+		},
+		{
+			name: "Success, the response is saved",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {
+					claim(true, nil)
+					e.repositoryMock.EXPECT().SaveIdempotencyResponse(gomock.Any(), "retry-key", http.StatusCreated, echo.MIMEApplicationJSONCharsetUTF8, []byte(`{"id":"new"}`+"\n")).Return(nil)
+				}},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":"new"}`,
+		},
+		{
+			name: "Success, a client error is saved too",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusBadRequest},
+			fields: fields{
+				mock: func() {
+					claim(true, nil)
+					e.repositoryMock.EXPECT().SaveIdempotencyResponse(gomock.Any(), "retry-key", http.StatusBadRequest, echo.MIMEApplicationJSONCharsetUTF8, []byte(`{"id":"new"}`+"\n")).Return(nil)
+				}},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: `{"id":"new"}`,
+		},
+		{
+			name: "Success, the key is released on a server error",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusInternalServerError},
+			fields: fields{
+				mock: func() {
+					claim(true, nil)
+					e.repositoryMock.EXPECT().ReleaseIdempotencyKey(gomock.Any(), "retry-key").Return(nil)
+				}},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: `{"id":"new"}`,
+		},
+		{
+			name: "Success, the key is released when the response can't be saved",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {
+					claim(true, nil)
+					e.repositoryMock.EXPECT().SaveIdempotencyResponse(gomock.Any(), "retry-key", http.StatusCreated, echo.MIMEApplicationJSONCharsetUTF8, gomock.Any()).Return(sql.ErrConnDone)
+					e.repositoryMock.EXPECT().ReleaseIdempotencyKey(gomock.Any(), "retry-key").Return(nil)
+				}},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":"new"}`,
+		},
+		{
+			name: "Success, a retry replays the saved response",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {
+					claim(false, nil)
+					e.repositoryMock.EXPECT().GetIdempotencyKey(gomock.Any(), "retry-key").Return(repository.IdempotencyKey{
+						Key: "retry-key", RequestHash: hash, StatusCode: &created, ContentType: echo.MIMEApplicationJSON, ResponseBody: []byte(`{"id":"first"}`),
+					}, nil)
+				}},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":"first"}`,
+			expectedReplayed:     "true",
+		},
+		{
+			name: "Failed, the key was used for another request",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {
+					claim(false, nil)
+					e.repositoryMock.EXPECT().GetIdempotencyKey(gomock.Any(), "retry-key").Return(repository.IdempotencyKey{
+						Key: "retry-key", RequestHash: "another-hash", StatusCode: &created, ContentType: echo.MIMEApplicationJSON, ResponseBody: []byte(`{"id":"first"}`),
+					}, nil)
+				}},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: `{"code":"idempotency_key_reused","detail":"Idempotency-Key was already used for another request","status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name: "Failed, the first request is still being processed",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {
+					claim(false, nil)
+					e.repositoryMock.EXPECT().GetIdempotencyKey(gomock.Any(), "retry-key").Return(repository.IdempotencyKey{
+						Key: "retry-key", RequestHash: hash,
+					}, nil)
+				}},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"code":"idempotency_key_in_progress","detail":"A request with this Idempotency-Key is still being processed","status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name: "Failed, the key was released in the meantime",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {
+					claim(false, nil)
+					e.repositoryMock.EXPECT().GetIdempotencyKey(gomock.Any(), "retry-key").Return(repository.IdempotencyKey{}, gorm.ErrRecordNotFound)
+				}},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: `{"code":"idempotency_key_in_progress","detail":"A request with this Idempotency-Key is still being processed","status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name: "Failed, got error from GetIdempotencyKey repo",
+			args: args{path: "/estate", key: "retry-key", reqBody: reqBody, status: http.StatusCreated},
+			fields: fields{
+				mock: func() {
+					claim(false, nil)
+					e.repositoryMock.EXPECT().GetIdempotencyKey(gomock.Any(), "retry-key").Return(repository.IdempotencyKey{}, errors.New("connection reset"))
+				}},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: `{"code":"internal_server_error","detail":"Oops, something wrong with the server. Please try again later","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			test.fields.mock()
+
+			router := echo.New()
+			router.Use(middleware)
+			// The handlers check they still get the whole body once it has been hashed
+			handler := func(ctx echo.Context) error {
+				var body generated.CreateEstateJSONBody
+				assert.NoError(e.T(), ctx.Bind(&body))
+				assert.Equal(e.T(), 10, body.Length)
+				return ctx.JSON(test.args.status, map[string]string{"id": "new"})
+			}
+			router.POST("/estate", handler)
+			router.POST("/estate/:estate_id/division", handler)
+
+			req := httptest.NewRequest(http.MethodPost, test.args.path, strings.NewReader(test.args.reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if test.args.key != "" {
+				req.Header.Set(idempotencyKeyHeader, test.args.key)
+			}
+			req = req.WithContext(repository.WithOrganisation(req.Context(), organisationID))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			assert.Equal(e.T(), test.expectedResponseBody, strings.TrimSpace(rec.Body.String()))
+			assert.Equal(e.T(), test.expectedReplayed, rec.Header().Get(idempotentReplayedHeader))
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestIdempotentOperations() {
+	swagger, err := generated.GetSwagger()
+	assert.NoError(e.T(), err)
+
+	operations := idempotentOperations(swagger)
+
+	assert.Equal(e.T(), map[string]bool{"POST /estate": true, "POST /estate/:estate_id/tree": true}, operations)
+}
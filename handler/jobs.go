@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"time"
+)
+
+// PurgeDeletedEstates hard deletes the estates whose retention period is over.
+func (s *Server) PurgeDeletedEstates(ctx context.Context) (purged int64, err error) {
+	return s.Repository.PurgeDeletedEstates(ctx, time.Now().Add(-s.EstateRetention))
+}
+
+// RunEstatePurge purges the deleted estates every interval until the context is done.
+func (s *Server) RunEstatePurge(ctx context.Context, interval time.Duration, logger echo.Logger) {
+	runEvery(ctx, interval, func() {
+		purged, err := s.PurgeDeletedEstates(ctx)
+		if err != nil {
+			logger.Errorf("failed to purge deleted estates: %v", err)
+		} else if purged > 0 {
+			logger.Infof("purged %d deleted estates", purged)
+		}
+	})
+}
+
+// PurgeExpiredIdempotencyKeys deletes the idempotency keys whose responses can't be replayed
+// anymore.
+func (s *Server) PurgeExpiredIdempotencyKeys(ctx context.Context) (purged int64, err error) {
+	return s.Repository.PurgeExpiredIdempotencyKeys(ctx, time.Now())
+}
+
+// RunIdempotencyKeyPurge purges the expired idempotency keys every interval until the context
+// is done.
+func (s *Server) RunIdempotencyKeyPurge(ctx context.Context, interval time.Duration, logger echo.Logger) {
+	runEvery(ctx, interval, func() {
+		purged, err := s.PurgeExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			logger.Errorf("failed to purge expired idempotency keys: %v", err)
+		} else if purged > 0 {
+			logger.Infof("purged %d expired idempotency keys", purged)
+		}
+	})
+}
+
+// snapshotDay returns the day a snapshot taken at a time is recorded for, in UTC.
+func snapshotDay(at time.Time) time.Time {
+	year, month, day := at.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// SnapshotEstateStats takes today's snapshot of the stats of the estates which don't have one yet.
+func (s *Server) SnapshotEstateStats(ctx context.Context) (taken int64, err error) {
+	return s.Repository.SnapshotEstateStats(ctx, snapshotDay(time.Now()))
+}
+
+// RunStatsSnapshots takes the daily snapshots of the estate stats every interval until the
+// context is done. Running more often than daily catches up on a day missed while the server
+// was down or the database failing, the snapshots already taken being skipped.
+func (s *Server) RunStatsSnapshots(ctx context.Context, interval time.Duration, logger echo.Logger) {
+	runEvery(ctx, interval, func() {
+		taken, err := s.SnapshotEstateStats(ctx)
+		if err != nil {
+			logger.Errorf("failed to snapshot the estate stats: %v", err)
+		} else if taken > 0 {
+			logger.Infof("snapshotted the stats of %d estates", taken)
+		}
+	})
+}
+
+// runEvery runs a job straight away then every interval until the context is done.
+func runEvery(ctx context.Context, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecomputeEstateStats rebuilds the materialised tree stats of an estate from its trees, or of
+// every estate when estateID is nil.
+func (s *Server) RecomputeEstateStats(ctx context.Context, estateID *string) error {
+	return s.Repository.RecomputeEstateStats(ctx, estateID)
+}
+
+// CheckEstateStats returns the materialised tree counts which don't match the trees, of an
+// estate or of every estate when estateID is nil.
+func (s *Server) CheckEstateStats(ctx context.Context, estateID *string) ([]repository.EstateStatDrift, error) {
+	return s.Repository.GetEstateStatsDrift(ctx, estateID)
+}
+
+// CreateOrganisation saves a new organisation, its ID being what its credentials are scoped to.
+func (s *Server) CreateOrganisation(ctx context.Context, name string) (repository.Organisation, error) {
+	organisation := repository.Organisation{Name: name}
+	err := s.Repository.CreateOrganisation(ctx, &organisation)
+
+	return organisation, err
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"time"
+)
+
+func (e *EndpointsTestSuite) TestPurgeDeletedEstates() {
+	type fields struct {
+		mock func(ctx context.Context)
+	}
+
+	e.server.EstateRetention = 30 * 24 * time.Hour
+
+	// The estates deleted before the retention period are the ones purged
+	deletedBefore := gomock.Cond(func(x any) bool {
+		deletedBefore, ok := x.(time.Time)
+		return ok && time.Since(deletedBefore).Round(time.Hour) == e.server.EstateRetention
+	})
+
+	tests := []struct {
+		name           string
+		fields         fields
+		expectedResult int64
+		expectedErr    error
+	}{
+		{
+			name: "Failed, got error from PurgeDeletedEstates repo",
+			fields: fields{
+				mock: func(ctx context.Context) {
+					e.repositoryMock.EXPECT().PurgeDeletedEstates(ctx, deletedBefore).Return(int64(0), sql.ErrConnDone)
+				},
+			},
+			expectedResult: 0,
+			expectedErr:    sql.ErrConnDone,
+		},
+		{
+			name: "Success",
+			fields: fields{
+				mock: func(ctx context.Context) {
+					e.repositoryMock.EXPECT().PurgeDeletedEstates(ctx, deletedBefore).Return(int64(2), nil)
+				},
+			},
+			expectedResult: 2,
+			expectedErr:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			ctx := context.Background()
+			test.fields.mock(ctx)
+
+			actualResult, actualErr := e.server.PurgeDeletedEstates(ctx)
+
+			assert.Equal(e.T(), test.expectedErr, actualErr)
+			assert.Equal(e.T(), test.expectedResult, actualResult)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestPurgeExpiredIdempotencyKeys() {
+	type fields struct {
+		mock func(ctx context.Context)
+	}
+
+	// The keys which expired by now are the ones purged
+	expiredBefore := gomock.Cond(func(x any) bool {
+		expiredBefore, ok := x.(time.Time)
+		return ok && time.Since(expiredBefore) < time.Minute
+	})
+
+	tests := []struct {
+		name           string
+		fields         fields
+		expectedResult int64
+		expectedErr    error
+	}{
+		{
+			name: "Failed, got error from PurgeExpiredIdempotencyKeys repo",
+			fields: fields{
+				mock: func(ctx context.Context) {
+					e.repositoryMock.EXPECT().PurgeExpiredIdempotencyKeys(ctx, expiredBefore).Return(int64(0), sql.ErrConnDone)
+				},
+			},
+			expectedResult: 0,
+			expectedErr:    sql.ErrConnDone,
+		},
+		{
+			name: "Success",
+			fields: fields{
+				mock: func(ctx context.Context) {
+					e.repositoryMock.EXPECT().PurgeExpiredIdempotencyKeys(ctx, expiredBefore).Return(int64(5), nil)
+				},
+			},
+			expectedResult: 5,
+			expectedErr:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			ctx := context.Background()
+			test.fields.mock(ctx)
+
+			actualResult, actualErr := e.server.PurgeExpiredIdempotencyKeys(ctx)
+
+			assert.Equal(e.T(), test.expectedErr, actualErr)
+			assert.Equal(e.T(), test.expectedResult, actualResult)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestSnapshotEstateStats() {
+	type fields struct {
+		mock func(ctx context.Context)
+	}
+
+	today := snapshotDay(time.Now())
+
+	tests := []struct {
+		name           string
+		fields         fields
+		expectedResult int64
+		expectedErr    error
+	}{
+		{
+			name: "Failed, got error from SnapshotEstateStats repo",
+			fields: fields{
+				mock: func(ctx context.Context) {
+					e.repositoryMock.EXPECT().SnapshotEstateStats(ctx, today).Return(int64(0), sql.ErrConnDone)
+				},
+			},
+			expectedResult: 0,
+			expectedErr:    sql.ErrConnDone,
+		},
+		{
+			name: "Success",
+			fields: fields{
+				mock: func(ctx context.Context) {
+					e.repositoryMock.EXPECT().SnapshotEstateStats(ctx, today).Return(int64(3), nil)
+				},
+			},
+			expectedResult: 3,
+			expectedErr:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			ctx := context.Background()
+			test.fields.mock(ctx)
+
+			actualResult, actualErr := e.server.SnapshotEstateStats(ctx)
+
+			assert.Equal(e.T(), test.expectedErr, actualErr)
+			assert.Equal(e.T(), test.expectedResult, actualResult)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestSnapshotDay() {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	assert.Equal(e.T(), time.Date(2024, 05, 01, 0, 0, 0, 0, time.UTC), snapshotDay(time.Date(2024, 05, 01, 23, 59, 0, 0, time.UTC)))
+	assert.Equal(e.T(), time.Date(2024, 04, 30, 0, 0, 0, 0, time.UTC), snapshotDay(time.Date(2024, 05, 01, 6, 0, 0, 0, jakarta)))
+}
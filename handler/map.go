@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+)
+
+const (
+	defaultMapSize = 800
+	// Tree heights are between 1 and 30 meters, see the CreateTree validation
+	minTreeHeight = 1
+	maxTreeHeight = 30
+)
+
+var (
+	mapSoilColor      = color.RGBA{R: 234, G: 221, B: 202, A: 255}
+	mapShortTreeColor = color.RGBA{R: 198, G: 232, B: 140, A: 255}
+	mapTallTreeColor  = color.RGBA{R: 16, G: 92, B: 40, A: 255}
+	mapDronePathColor = color.RGBA{R: 220, G: 38, B: 38, A: 255}
+	// The cells outside of the boundary of the estate, which can't be planted
+	mapMaskedColor = color.RGBA{R: 189, G: 189, B: 189, A: 255}
+)
+
+// estateMap is the layout of an estate picture. The estate is drawn in square cells
+// of one or more plots, with the first row of plots at the bottom of the picture.
+type estateMap struct {
+	columns    int
+	rows       int
+	cellPixels int
+	cells      []repository.GridCell
+	// maskedCells are the cells without any plot inside the boundary of the estate
+	maskedCells []image.Point
+	// dronePath are the cells the drone flies over in order, none when it isn't drawn
+	dronePath []image.Point
+}
+
+// mapCellSize returns how many plots a side of a cell covers so the longest
+// side of the estate fits in the requested image size.
+func mapCellSize(estate repository.Estate, size int) int {
+	longest := max(estate.Length, estate.Width)
+	return (longest + size - 1) / size
+}
+
+func newEstateMap(estate repository.Estate, size int, cell int, cells []repository.GridCell, dronePath bool) estateMap {
+	columns := (estate.Length + cell - 1) / cell
+	rows := (estate.Width + cell - 1) / cell
+
+	m := estateMap{
+		columns:     columns,
+		rows:        rows,
+		cellPixels:  max(size/max(columns, rows), 1),
+		cells:       cells,
+		maskedCells: maskedCells(estate, cell, columns, rows),
+	}
+	if dronePath {
+		m.dronePath = dronePathCells(estate, cell, rows)
+	}
+
+	return m
+}
+
+// maskedCells returns the cells of the estate without any plot inside its boundary, which are
+// none when it has no boundary. The runs of each row of plots are walked once rather than the
+// plots of each cell, so a big estate isn't walked plot by plot.
+func maskedCells(estate repository.Estate, cell int, columns int, rows int) (masked []image.Point) {
+	if len(estate.Boundary) == 0 {
+		return nil
+	}
+
+	mask := newPlotMask(estate)
+	inside := make([]bool, columns*rows)
+	for y := 1; y <= estate.Width; y++ {
+		for _, run := range mask.rowRuns(y) {
+			for cellX := (run.from - 1) / cell; cellX <= (run.to-1)/cell; cellX++ {
+				inside[(y-1)/cell*columns+cellX] = true
+			}
+		}
+	}
+
+	for cellY := 0; cellY < rows; cellY++ {
+		for cellX := 0; cellX < columns; cellX++ {
+			if !inside[cellY*columns+cellX] {
+				masked = append(masked, image.Pt(cellX, cellY))
+			}
+		}
+	}
+
+	return
+}
+
+// dronePathCells returns the cells the drone flies over, back and forth along each row of cells
+// from the first plot inside the boundary of its rows to the last one, the rows of cells without
+// any plot inside being skipped. The same runs of plots as the drone plan are walked, so the path
+// drawn flies over the plots the plan does.
+func dronePathCells(estate repository.Estate, cell int, rows int) (path []image.Point) {
+	mask := newPlotMask(estate)
+	for cellY := 0; cellY < rows; cellY++ {
+		from, to := -1, -1
+		for y := cellY*cell + 1; y <= min((cellY+1)*cell, estate.Width); y++ {
+			runs := mask.rowRuns(y)
+			if len(runs) == 0 {
+				continue
+			}
+			if from < 0 || runs[0].from < from {
+				from = runs[0].from
+			}
+			to = max(to, runs[len(runs)-1].to)
+		}
+		if from < 0 {
+			continue
+		}
+
+		// Every other row of cells flown over is flown back
+		fromX, toX := (from-1)/cell, (to-1)/cell
+		if len(path)/2%2 == 1 {
+			fromX, toX = toX, fromX
+		}
+		path = append(path, image.Pt(fromX, cellY), image.Pt(toX, cellY))
+	}
+
+	return
+}
+
+func (m estateMap) width() int {
+	return m.columns * m.cellPixels
+}
+
+func (m estateMap) height() int {
+	return m.rows * m.cellPixels
+}
+
+// cellOrigin returns the top left pixel of a cell
+func (m estateMap) cellOrigin(cellX int, cellY int) (x int, y int) {
+	return cellX * m.cellPixels, (m.rows - 1 - cellY) * m.cellPixels
+}
+
+// dronePathPoints returns the centre of the cells in the order the drone flies over them.
+func (m estateMap) dronePathPoints() (points []image.Point) {
+	half := m.cellPixels / 2
+	for _, cell := range m.dronePath {
+		x, y := m.cellOrigin(cell.X, cell.Y)
+		points = append(points, image.Pt(x+half, y+half))
+	}
+
+	return
+}
+
+func (m estateMap) dronePathWidth() int {
+	return max(m.cellPixels/4, 1)
+}
+
+// heightColor blends the tree color from light to dark green as the trees get taller
+func heightColor(height float64) color.RGBA {
+	ratio := (height - minTreeHeight) / (maxTreeHeight - minTreeHeight)
+	ratio = min(max(ratio, 0), 1)
+
+	blend := func(from uint8, to uint8) uint8 {
+		return uint8(float64(from) + (float64(to)-float64(from))*ratio)
+	}
+
+	return color.RGBA{
+		R: blend(mapShortTreeColor.R, mapTallTreeColor.R),
+		G: blend(mapShortTreeColor.G, mapTallTreeColor.G),
+		B: blend(mapShortTreeColor.B, mapTallTreeColor.B),
+		A: 255,
+	}
+}
+
+func (m estateMap) renderPNG() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, m.width(), m.height()))
+	draw.Draw(img, img.Bounds(), image.NewUniform(mapSoilColor), image.Point{}, draw.Src)
+
+	for _, cell := range m.maskedCells {
+		x, y := m.cellOrigin(cell.X, cell.Y)
+		rect := image.Rect(x, y, x+m.cellPixels, y+m.cellPixels)
+		draw.Draw(img, rect, image.NewUniform(mapMaskedColor), image.Point{}, draw.Src)
+	}
+
+	for _, cell := range m.cells {
+		x, y := m.cellOrigin(cell.CellX, cell.CellY)
+		rect := image.Rect(x, y, x+m.cellPixels, y+m.cellPixels)
+		draw.Draw(img, rect, image.NewUniform(heightColor(cell.AvgHeight)), image.Point{}, draw.Src)
+	}
+
+	if len(m.dronePath) > 0 {
+		// The drone path only moves along rows and columns, so each leg is a thin rectangle
+		lineWidth := m.dronePathWidth()
+		points := m.dronePathPoints()
+		for i := 1; i < len(points); i++ {
+			from, to := points[i-1], points[i]
+			rect := image.Rect(from.X, from.Y, to.X, to.Y).Canon()
+			rect.Max = rect.Max.Add(image.Pt(lineWidth, lineWidth))
+			draw.Draw(img, rect, image.NewUniform(mapDronePathColor), image.Point{}, draw.Src)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m estateMap) renderSVG() []byte {
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		m.width(), m.height(), m.width(), m.height())
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="%s"/>`, m.width(), m.height(), hexColor(mapSoilColor))
+
+	for _, cell := range m.maskedCells {
+		x, y := m.cellOrigin(cell.X, cell.Y)
+		fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>Outside of the boundary</title></rect>`,
+			x, y, m.cellPixels, m.cellPixels, hexColor(mapMaskedColor))
+	}
+
+	for _, cell := range m.cells {
+		x, y := m.cellOrigin(cell.CellX, cell.CellY)
+		fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%d trees, average height %.1f m</title></rect>`,
+			x, y, m.cellPixels, m.cellPixels, hexColor(heightColor(cell.AvgHeight)), cell.TreeCount, cell.AvgHeight)
+	}
+
+	if len(m.dronePath) > 0 {
+		dronePathPoints := m.dronePathPoints()
+		points := make([]string, 0, len(dronePathPoints))
+		for _, point := range dronePathPoints {
+			points = append(points, fmt.Sprintf("%d,%d", point.X, point.Y))
+		}
+		fmt.Fprintf(&svg, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%d"/>`,
+			strings.Join(points, " "), hexColor(mapDronePathColor), m.dronePathWidth())
+	}
+
+	svg.WriteString(`</svg>`)
+
+	return []byte(svg.String())
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// newProblem returns the RFC 7807 problem of a status. Its type is left to about:blank, the
+// code telling the problems apart.
+func newProblem(status int, code string, detail string) generated.Problem {
+	return generated.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// problem answers a request with an RFC 7807 problem.
+func problem(ctx echo.Context, status int, code string, detail string) error {
+	return sendProblem(ctx, status, newProblem(status, code, detail))
+}
+
+// sendProblem sends a problem, or a problem extended with more members, as application/problem+json.
+func sendProblem(ctx echo.Context, status int, body interface{}) error {
+	ctx.Response().Header().Set(echo.HeaderContentType, problemContentType)
+	return ctx.JSON(status, body)
+}
+
+// validationProblem answers a request which failed validation with the fields it got wrong,
+// the rule each of them breaks and its limit.
+func validationProblem(ctx echo.Context, err error) error {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return problem(ctx, http.StatusBadRequest, "invalid_input", "Invalid input")
+	}
+
+	violations := make([]generated.FieldViolation, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		violation := generated.FieldViolation{
+			Field: fieldPath(fieldErr),
+			Rule:  fieldErr.Tag(),
+		}
+		if limit := fieldErr.Param(); limit != "" {
+			violation.Limit = &limit
+		}
+		violations = append(violations, violation)
+	}
+
+	body := newProblem(http.StatusBadRequest, "validation_failed", "Some fields are invalid")
+	body.Errors = &violations
+
+	return sendProblem(ctx, http.StatusBadRequest, body)
+}
+
+// treesOutOfBoundsProblem answers an estate update with the trees it would leave outside of the
+// estate's area.
+func treesOutOfBoundsProblem(ctx echo.Context, trees []generated.Tree) error {
+	return treesProblem(ctx, "trees_out_of_bounds", "Trees would be left outside of the estate's area", trees)
+}
+
+// blocksOutOfBoundsProblem answers a resize which would leave the blocks it lists outside of the estate.
+func blocksOutOfBoundsProblem(ctx echo.Context, blocks []generated.Block) error {
+	p := newProblem(http.StatusConflict, "blocks_out_of_bounds", "Blocks would be left outside of the estate's area, delete them first")
+
+	return sendProblem(ctx, http.StatusConflict, generated.BlocksOutOfBoundsProblem{
+		Type:   p.Type,
+		Title:  p.Title,
+		Status: p.Status,
+		Detail: p.Detail,
+		Code:   p.Code,
+		Blocks: blocks,
+	})
+}
+
+// treesProblem answers a conflict over the trees it lists.
+func treesProblem(ctx echo.Context, code string, detail string, trees []generated.Tree) error {
+	p := newProblem(http.StatusConflict, code, detail)
+
+	return sendProblem(ctx, http.StatusConflict, generated.TreesOutOfBoundsProblem{
+		Type:   p.Type,
+		Title:  p.Title,
+		Status: p.Status,
+		Detail: p.Detail,
+		Code:   p.Code,
+		Trees:  trees,
+	})
+}
+
+// fieldPath returns the path of an invalid field from the validated struct, such as
+// boundary[2].latitude.
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, ok := strings.Cut(fieldErr.Namespace(), ".")
+	if !ok {
+		return fieldErr.Field()
+	}
+
+	return path
+}
+
+// JSONFieldName names the fields of the validation errors after their JSON key, as the clients
+// know them. It is meant for validator.Validate.RegisterTagNameFunc.
+func JSONFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+// ProblemErrorHandler sends the errors left to echo, such as the parameters the generated
+// wrappers fail to bind or the unknown routes, as problems too.
+func ProblemErrorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+
+	status, detail := http.StatusInternalServerError, "Oops, something wrong with the server. Please try again later"
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		status, detail = httpErr.Code, http.StatusText(httpErr.Code)
+		if message, ok := httpErr.Message.(string); ok {
+			detail = message
+		}
+	}
+
+	// The bad requests share the code of the ones the handlers reject
+	code := "invalid_input"
+	if status != http.StatusBadRequest {
+		code = strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	}
+
+	if ctx.Request().Method == http.MethodHead {
+		err = ctx.NoContent(status)
+	} else {
+		err = problem(ctx, status, code, detail)
+	}
+	if err != nil {
+		ctx.Logger().Error(err)
+	}
+}
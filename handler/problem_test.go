@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+)
+
+func (e *EndpointsTestSuite) TestValidationProblem() {
+	type vertex struct {
+		Latitude float64 `json:"latitude" validate:"min=-90,max=90"`
+	}
+	type body struct {
+		Name     string   `json:"name,omitempty" validate:"required"`
+		Bearing  int      `json:"bearing" validate:"lt=360"`
+		Boundary []vertex `json:"boundary" validate:"dive"`
+		Internal int      `json:"-" validate:"min=1"`
+	}
+
+	validate := validator.New()
+	validate.RegisterTagNameFunc(JSONFieldName)
+	maxBearing, maxLatitude, minInternal := "360", "90", "1"
+
+	tests := []struct {
+		name               string
+		err                error
+		expectedCode       string
+		expectedViolations *[]generated.FieldViolation
+	}{
+		{
+			name:         "Fields breaking their rules",
+			err:          validate.Struct(body{Bearing: 360, Boundary: []vertex{{Latitude: 0}, {Latitude: 91}}}),
+			expectedCode: "validation_failed",
+			expectedViolations: &[]generated.FieldViolation{
+				{Field: "name", Rule: "required"},
+				{Field: "bearing", Rule: "lt", Limit: &maxBearing},
+				{Field: "boundary[1].latitude", Rule: "max", Limit: &maxLatitude},
+				{Field: "Internal", Rule: "min", Limit: &minInternal},
+			},
+		},
+		{
+			name:         "Not a validation error",
+			err:          validate.Struct(1),
+			expectedCode: "invalid_input",
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(httptest.NewRequest(http.MethodPost, "/estate", nil), rec)
+
+			err := validationProblem(ctx, test.err)
+			assert.NoError(e.T(), err)
+
+			var resp generated.Problem
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(e.T(), err)
+
+			assert.Equal(e.T(), http.StatusBadRequest, rec.Code)
+			assert.Equal(e.T(), problemContentType, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(e.T(), test.expectedCode, resp.Code)
+			assert.Equal(e.T(), test.expectedViolations, resp.Errors)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestProblemErrorHandler() {
+	tests := []struct {
+		name               string
+		method             string
+		err                error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "Parameter failing to bind",
+			method:             http.MethodGet,
+			err:                echo.NewHTTPError(http.StatusBadRequest, "Invalid format for parameter estateId"),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"code":"invalid_input","detail":"Invalid format for parameter estateId","status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+		{
+			name:               "Unknown route",
+			method:             http.MethodGet,
+			err:                echo.ErrNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"code":"not_found","detail":"Not Found","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+		{
+			name:               "Any other error",
+			method:             http.MethodGet,
+			err:                errors.New("connection reset"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"code":"internal_server_error","detail":"Oops, something wrong with the server. Please try again later","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+		},
+		{
+			name:               "HEAD request",
+			method:             http.MethodHead,
+			err:                echo.ErrNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       "",
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			rec := httptest.NewRecorder()
+			ctx := e.echo.NewContext(httptest.NewRequest(test.method, "/estate", nil), rec)
+
+			ProblemErrorHandler(test.err, ctx)
+
+			assert.Equal(e.T(), test.expectedStatusCode, rec.Code)
+			if test.expectedBody != "" {
+				assert.Equal(e.T(), problemContentType, rec.Header().Get(echo.HeaderContentType))
+				assert.JSONEq(e.T(), test.expectedBody, rec.Body.String())
+			} else {
+				assert.Empty(e.T(), rec.Body.String())
+			}
+		})
+	}
+}
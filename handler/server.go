@@ -2,18 +2,23 @@ package handler
 
 import (
 	"github.com/SawitProRecruitment/UserService/repository"
+	"time"
 )
 
 type Server struct {
 	Repository repository.RepositoryInterface
+	// EstateRetention is how long a deleted estate can still be restored before it is purged.
+	EstateRetention time.Duration
 }
 
 type NewServerOptions struct {
-	Repository repository.RepositoryInterface
+	Repository      repository.RepositoryInterface
+	EstateRetention time.Duration
 }
 
 func NewServer(opts NewServerOptions) *Server {
 	return &Server{
-		Repository: opts.Repository,
+		Repository:      opts.Repository,
+		EstateRetention: opts.EstateRetention,
 	}
 }
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"cmp"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"slices"
+)
+
+const defaultHistogramBucketSize = 5
+
+var defaultPercentiles = []float64{25, 75, 90}
+
+// statsOptions returns the percentiles, from 0 to 100, and the histogram bucket size of
+// a stats request, falling back to their defaults when not given.
+func statsOptions(percentiles *[]float64, bucketSize *int) ([]float64, int) {
+	requested, size := defaultPercentiles, defaultHistogramBucketSize
+	if percentiles != nil {
+		requested = *percentiles
+	}
+	if bucketSize != nil {
+		size = *bucketSize
+	}
+
+	return requested, size
+}
+
+// percentileFractions converts percentiles from 0 to 100 to the fractions the database expects.
+func percentileFractions(percentiles []float64) []float64 {
+	fractions := make([]float64, 0, len(percentiles))
+	for _, percentile := range percentiles {
+		fractions = append(fractions, percentile/100)
+	}
+
+	return fractions
+}
+
+// heightStats converts the height stats aggregated by the database, for the requested
+// percentiles in the same order.
+func heightStats(stats repository.TreeHeightStats, percentiles []float64, bucketSize int) (resp generated.GetEstateStatsResponse) {
+	resp.Percentiles = []generated.HeightPercentile{}
+	resp.Histogram = heightHistogram(stats, bucketSize)
+
+	if stats.Count == 0 {
+		return
+	}
+
+	resp.Count = stats.Count
+	resp.Max = stats.MaxHeight
+	resp.Min = stats.MinHeight
+	resp.Median = stats.MedianHeight
+	resp.Mean = stats.MeanHeight
+	resp.Stddev = stats.StddevHeight
+
+	for i, percentile := range percentiles {
+		if i >= len(stats.Percentiles) {
+			break
+		}
+		resp.Percentiles = append(resp.Percentiles, generated.HeightPercentile{
+			Percentile: percentile,
+			Value:      stats.Percentiles[i],
+		})
+	}
+
+	return
+}
+
+// heightHistogram lays the counted buckets out on multiples of the bucket size, from the
+// bucket of the lowest tree to the one of the highest.
+func heightHistogram(stats repository.TreeHeightStats, bucketSize int) (histogram generated.HeightHistogram) {
+	histogram.BucketSize = bucketSize
+	histogram.Buckets = []generated.HeightHistogramBucket{}
+
+	if stats.Count == 0 {
+		return
+	}
+
+	first := stats.MinHeight / bucketSize
+	last := stats.MaxHeight / bucketSize
+	for bucket := first; bucket <= last; bucket++ {
+		histogram.Buckets = append(histogram.Buckets, generated.HeightHistogramBucket{
+			From: bucket * bucketSize,
+			To:   (bucket + 1) * bucketSize,
+		})
+	}
+	for _, bucket := range stats.Histogram {
+		if bucket.Bucket >= first && bucket.Bucket <= last {
+			histogram.Buckets[bucket.Bucket-first].Count = bucket.Count
+		}
+	}
+
+	return
+}
+
+// portfolioRanking converts the estates of a portfolio, ranked by their density of trees and
+// by their median tree height, highest first, and ordered by the rank_by ranking. Estates with
+// the same value share their rank and keep the order of the portfolio.
+func portfolioRanking(estates []repository.PortfolioEstate, rankBy generated.GetPortfolioStatsParamsRankBy) []generated.PortfolioEstate {
+	ranking := make([]generated.PortfolioEstate, 0, len(estates))
+	for _, estate := range estates {
+		plotCount := estate.PlotCount
+		resp := generated.PortfolioEstate{
+			Id:           stringToUUID(estate.ID),
+			Name:         estate.Name,
+			Owner:        estate.Owner,
+			PlotCount:    plotCount,
+			AreaHectares: float64(plotCount) / plotsPerHectare,
+			TreeCount:    estate.Stats.Count,
+			MedianHeight: estate.Stats.MedianHeight,
+		}
+		if plotCount > 0 {
+			resp.Density = float64(estate.Stats.Count*plotsPerHectare) / float64(plotCount)
+		}
+		ranking = append(ranking, resp)
+	}
+
+	// rank returns the rank of each estate from the highest value, ties sharing the best rank
+	rank := func(value func(estate generated.PortfolioEstate) float64) []int {
+		order := make([]int, len(ranking))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(value(ranking[b]), value(ranking[a]))
+		})
+
+		ranks := make([]int, len(ranking))
+		for i, index := range order {
+			if i > 0 && value(ranking[index]) == value(ranking[order[i-1]]) {
+				ranks[index] = ranks[order[i-1]]
+				continue
+			}
+			ranks[index] = i + 1
+		}
+		return ranks
+	}
+	densityRanks := rank(func(estate generated.PortfolioEstate) float64 { return estate.Density })
+	medianHeightRanks := rank(func(estate generated.PortfolioEstate) float64 { return estate.MedianHeight })
+	for i := range ranking {
+		ranking[i].DensityRank = densityRanks[i]
+		ranking[i].MedianHeightRank = medianHeightRanks[i]
+	}
+
+	slices.SortStableFunc(ranking, func(a, b generated.PortfolioEstate) int {
+		if rankBy == generated.MedianHeight {
+			return a.MedianHeightRank - b.MedianHeightRank
+		}
+		return a.DensityRank - b.DensityRank
+	})
+
+	return ranking
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func (e *EndpointsTestSuite) TestHeightStats() {
+	type args struct {
+		stats       repository.TreeHeightStats
+		percentiles []float64
+		bucketSize  int
+	}
+
+	tests := []struct {
+		name         string
+		args         args
+		expectedResp generated.GetEstateStatsResponse
+	}{
+		{
+			name: "No trees",
+			args: args{
+				percentiles: defaultPercentiles,
+				bucketSize:  5,
+			},
+			expectedResp: generated.GetEstateStatsResponse{
+				Percentiles: []generated.HeightPercentile{},
+				Histogram:   generated.HeightHistogram{BucketSize: 5, Buckets: []generated.HeightHistogramBucket{}},
+			},
+		},
+		{
+			name: "One tree",
+			args: args{
+				stats: repository.TreeHeightStats{
+					Count:        1,
+					MinHeight:    7,
+					MaxHeight:    7,
+					MeanHeight:   7,
+					MedianHeight: 7,
+					Percentiles:  repository.HeightPercentiles{7, 7, 7},
+					Histogram:    []repository.TreeHeightBucket{{Bucket: 1, Count: 1}},
+				},
+				percentiles: defaultPercentiles,
+				bucketSize:  5,
+			},
+			expectedResp: generated.GetEstateStatsResponse{
+				Count:  1,
+				Min:    7,
+				Max:    7,
+				Median: 7,
+				Mean:   7,
+				Percentiles: []generated.HeightPercentile{
+					{Percentile: 25, Value: 7},
+					{Percentile: 75, Value: 7},
+					{Percentile: 90, Value: 7},
+				},
+				Histogram: generated.HeightHistogram{BucketSize: 5, Buckets: []generated.HeightHistogramBucket{{From: 5, To: 10, Count: 1}}},
+			},
+		},
+		{
+			name: "The empty buckets between the lowest and the highest trees are kept",
+			args: args{
+				stats: repository.TreeHeightStats{
+					Count:        4,
+					MinHeight:    1,
+					MaxHeight:    7,
+					MeanHeight:   3,
+					StddevHeight: 2.345207879911715,
+					MedianHeight: 2,
+					Percentiles:  repository.HeightPercentiles{1, 1.3, 7},
+					Histogram:    []repository.TreeHeightBucket{{Bucket: 0, Count: 1}, {Bucket: 1, Count: 2}, {Bucket: 3, Count: 1}},
+				},
+				percentiles: []float64{0, 10, 100},
+				bucketSize:  2,
+			},
+			expectedResp: generated.GetEstateStatsResponse{
+				Count:  4,
+				Min:    1,
+				Max:    7,
+				Median: 2,
+				Mean:   3,
+				Stddev: 2.345207879911715,
+				Percentiles: []generated.HeightPercentile{
+					{Percentile: 0, Value: 1},
+					{Percentile: 10, Value: 1.3},
+					{Percentile: 100, Value: 7},
+				},
+				Histogram: generated.HeightHistogram{
+					BucketSize: 2,
+					Buckets: []generated.HeightHistogramBucket{
+						{From: 0, To: 2, Count: 1},
+						{From: 2, To: 4, Count: 2},
+						{From: 4, To: 6, Count: 0},
+						{From: 6, To: 8, Count: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "No percentiles requested",
+			args: args{
+				stats: repository.TreeHeightStats{
+					Count:        3,
+					MinHeight:    2,
+					MaxHeight:    10,
+					MeanHeight:   5,
+					StddevHeight: 3.559026084010437,
+					MedianHeight: 3,
+					Histogram:    []repository.TreeHeightBucket{{Bucket: 0, Count: 2}, {Bucket: 2, Count: 1}},
+				},
+				percentiles: []float64{},
+				bucketSize:  5,
+			},
+			expectedResp: generated.GetEstateStatsResponse{
+				Count:       3,
+				Min:         2,
+				Max:         10,
+				Median:      3,
+				Mean:        5,
+				Stddev:      3.559026084010437,
+				Percentiles: []generated.HeightPercentile{},
+				Histogram: generated.HeightHistogram{
+					BucketSize: 5,
+					Buckets: []generated.HeightHistogramBucket{
+						{From: 0, To: 5, Count: 2},
+						{From: 5, To: 10, Count: 0},
+						{From: 10, To: 15, Count: 1},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			resp := heightStats(test.args.stats, test.args.percentiles, test.args.bucketSize)
+
+			assert.Equal(e.T(), test.expectedResp, resp)
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestStatsOptions() {
+	percentiles, bucketSize := statsOptions(nil, nil)
+	assert.Equal(e.T(), []float64{25, 75, 90}, percentiles)
+	assert.Equal(e.T(), 5, bucketSize)
+	assert.Equal(e.T(), []float64{0.25, 0.75, 0.9}, percentileFractions(percentiles))
+
+	size := 2
+	percentiles, bucketSize = statsOptions(&[]float64{50}, &size)
+	assert.Equal(e.T(), []float64{50}, percentiles)
+	assert.Equal(e.T(), 2, bucketSize)
+}
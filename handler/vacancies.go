@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"slices"
+)
+
+const defaultRegionCount = 5
+
+// vacantRun is a run of consecutive empty plots of row y.
+type vacantRun struct {
+	y int
+	plotRun
+}
+
+// maskRuns returns the runs of the plots inside the mask, ordered by row then by x, for the
+// repository to find the empty plots among them. The estate is walked run by run rather than
+// plot by plot, so the work grows with the rows but not the area.
+func maskRuns(mask plotMask) (runs []repository.PlotRun) {
+	for y := mask.yFrom; y <= mask.yTo; y++ {
+		for _, run := range mask.rowRuns(y) {
+			runs = append(runs, repository.PlotRun{Y: y, XFrom: run.from, XTo: run.to})
+		}
+	}
+
+	return
+}
+
+// vacantRegions groups the runs into the regions of empty plots connected by their sides,
+// largest first. The runs must be ordered by row then by x, as the repository returns them.
+func vacantRegions(runs []vacantRun) []generated.EmptyRegion {
+	parents := make([]int, len(runs))
+	for i := range parents {
+		parents[i] = i
+	}
+	find := func(i int) int {
+		for parents[i] != i {
+			parents[i] = parents[parents[i]]
+			i = parents[i]
+		}
+		return i
+	}
+
+	// Sweep each row against the one below it, the runs of a row don't overlap each other
+	previousFrom, previousTo := 0, 0
+	for rowFrom := 0; rowFrom < len(runs); {
+		rowTo := rowFrom
+		for rowTo < len(runs) && runs[rowTo].y == runs[rowFrom].y {
+			rowTo++
+		}
+
+		if previousTo > previousFrom && runs[previousFrom].y == runs[rowFrom].y-1 {
+			i, j := previousFrom, rowFrom
+			for i < previousTo && j < rowTo {
+				if runs[i].from <= runs[j].to && runs[j].from <= runs[i].to {
+					parents[find(i)] = find(j)
+				}
+				if runs[i].to < runs[j].to {
+					i++
+				} else {
+					j++
+				}
+			}
+		}
+
+		previousFrom, previousTo = rowFrom, rowTo
+		rowFrom = rowTo
+	}
+
+	regions := []generated.EmptyRegion{}
+	indexes := map[int]int{}
+	for i, run := range runs {
+		root := find(i)
+		index, ok := indexes[root]
+		if !ok {
+			index = len(regions)
+			indexes[root] = index
+			regions = append(regions, generated.EmptyRegion{XFrom: run.from, XTo: run.to, YFrom: run.y, YTo: run.y})
+		}
+
+		region := &regions[index]
+		region.PlotCount += run.to - run.from + 1
+		region.XFrom = min(region.XFrom, run.from)
+		region.XTo = max(region.XTo, run.to)
+		region.YFrom = min(region.YFrom, run.y)
+		region.YTo = max(region.YTo, run.y)
+	}
+
+	// Regions are created in the order of their first run, which keeps ties ordered by position
+	slices.SortStableFunc(regions, func(a, b generated.EmptyRegion) int {
+		return b.PlotCount - a.PlotCount
+	})
+
+	return regions
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func (e *EndpointsTestSuite) TestMaskRuns() {
+	type args struct {
+		estate repository.Estate
+	}
+
+	tests := []struct {
+		name         string
+		args         args
+		expectedRuns []repository.PlotRun
+	}{
+		{
+			name: "Estate without a boundary, a run per row",
+			args: args{
+				estate: repository.Estate{Length: 3, Width: 2},
+			},
+			expectedRuns: []repository.PlotRun{
+				{Y: 1, XFrom: 1, XTo: 3},
+				{Y: 2, XFrom: 1, XTo: 3},
+			},
+		},
+		{
+			name: "Plots outside of the boundary are left out of the runs",
+			args: args{
+				estate: repository.Estate{
+					Length:   3,
+					Width:    2,
+					Boundary: repository.Boundary{{X: 0, Y: 0}, {X: 3, Y: 0}, {X: 3, Y: 2}, {X: 2, Y: 2}, {X: 2, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 0, Y: 2}},
+				},
+			},
+			expectedRuns: []repository.PlotRun{
+				{Y: 1, XFrom: 1, XTo: 3},
+				{Y: 2, XFrom: 1, XTo: 1},
+				{Y: 2, XFrom: 3, XTo: 3},
+			},
+		},
+		{
+			name: "Estate without any plot",
+			args: args{
+				estate: repository.Estate{},
+			},
+			expectedRuns: []repository.PlotRun(nil),
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			assert.Equal(e.T(), test.expectedRuns, maskRuns(newPlotMask(test.args.estate)))
+		})
+	}
+}
+
+func (e *EndpointsTestSuite) TestVacantRegions() {
+	type args struct {
+		runs []vacantRun
+	}
+
+	tests := []struct {
+		name            string
+		args            args
+		expectedRegions []generated.EmptyRegion
+	}{
+		{
+			name:            "No runs",
+			args:            args{},
+			expectedRegions: []generated.EmptyRegion{},
+		},
+		{
+			name: "Runs touching by a corner only are apart, a U shape is one region",
+			args: args{
+				runs: []vacantRun{
+					{y: 1, plotRun: plotRun{from: 1, to: 1}},
+					{y: 1, plotRun: plotRun{from: 4, to: 6}},
+					{y: 2, plotRun: plotRun{from: 2, to: 2}},
+					{y: 2, plotRun: plotRun{from: 4, to: 4}},
+					{y: 2, plotRun: plotRun{from: 6, to: 6}},
+					{y: 4, plotRun: plotRun{from: 1, to: 2}},
+				},
+			},
+			expectedRegions: []generated.EmptyRegion{
+				{PlotCount: 5, XFrom: 4, XTo: 6, YFrom: 1, YTo: 2},
+				{PlotCount: 2, XFrom: 1, XTo: 2, YFrom: 4, YTo: 4},
+				{PlotCount: 1, XFrom: 1, XTo: 1, YFrom: 1, YTo: 1},
+				{PlotCount: 1, XFrom: 2, XTo: 2, YFrom: 2, YTo: 2},
+			},
+		},
+		{
+			name: "Two regions joined by a later row",
+			args: args{
+				runs: []vacantRun{
+					{y: 1, plotRun: plotRun{from: 1, to: 1}},
+					{y: 1, plotRun: plotRun{from: 3, to: 3}},
+					{y: 2, plotRun: plotRun{from: 1, to: 1}},
+					{y: 2, plotRun: plotRun{from: 3, to: 3}},
+					{y: 3, plotRun: plotRun{from: 1, to: 3}},
+				},
+			},
+			expectedRegions: []generated.EmptyRegion{
+				{PlotCount: 7, XFrom: 1, XTo: 3, YFrom: 1, YTo: 3},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e.Suite.Run(test.name, func() {
+			assert.Equal(e.T(), test.expectedRegions, vacantRegions(test.args.runs))
+		})
+	}
+}
@@ -3,9 +3,74 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"slices"
+	"strings"
+	"time"
 )
 
+// CreateOrganisation saves an organisation, it isn't scoped to the organisation of the context.
+func (r *Repository) CreateOrganisation(ctx context.Context, newOrganisation *Organisation) (err error) {
+	result := r.Db.WithContext(ctx).Create(newOrganisation)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	if result.RowsAffected < 1 {
+		err = errors.New("Insert operation failed because rows affected is 0")
+		return
+	}
+
+	return
+}
+
+// ownEstates narrows an estates query down to the estates of the organisation of its context,
+// failing it without one.
+func ownEstates(db *gorm.DB) *gorm.DB {
+	organisationID, ok := OrganisationFromContext(db.Statement.Context)
+	if !ok {
+		db.AddError(ErrNoOrganisation)
+		return db
+	}
+
+	return db.Where("estates.organisation_id", organisationID)
+}
+
+// inOwnEstates narrows a query of what is in the estates down to the rows whose estate, in
+// column, belongs to the organisation of its context and isn't deleted, failing it without one.
+func inOwnEstates(column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		organisationID, ok := OrganisationFromContext(db.Statement.Context)
+		if !ok {
+			db.AddError(ErrNoOrganisation)
+			return db
+		}
+
+		return db.Where(column+" IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Model(&Estate{}).Select("id").Where("organisation_id", organisationID))
+	}
+}
+
+// findOwnEstate checks an estate belongs to the organisation of the context before something is
+// created in it, returning gorm.ErrRecordNotFound when it doesn't.
+func findOwnEstate(tx *gorm.DB, estateID string) error {
+	return tx.Model(&Estate{}).Select("id").Where("id", estateID).Scopes(ownEstates).First(&Estate{}).Error
+}
+
+// CreateEstate saves an estate in the organisation of the context.
 func (r *Repository) CreateEstate(ctx context.Context, newEstate *Estate) (err error) {
+	organisationID, ok := OrganisationFromContext(ctx)
+	if !ok {
+		err = ErrNoOrganisation
+		return
+	}
+	newEstate.OrganisationID = organisationID
+
 	result := r.Db.WithContext(ctx).Create(newEstate)
 	if result.Error != nil {
 		err = result.Error
@@ -21,7 +86,66 @@ func (r *Repository) CreateEstate(ctx context.Context, newEstate *Estate) (err e
 }
 
 func (r *Repository) GetEstateByID(ctx context.Context, estateID string) (estate Estate, err error) {
-	result := r.Db.WithContext(ctx).Select("id", "width", "length").Where("id", estateID).First(&estate)
+	result := r.Db.WithContext(ctx).Where("id", estateID).Scopes(ownEstates).First(&estate)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// estateSorts maps the sorts of an estate listing to their ORDER BY clause.
+var estateSorts = map[string]string{
+	"created_at":  "estates.created_at ASC",
+	"-created_at": "estates.created_at DESC",
+	"name":        "estates.name ASC NULLS LAST",
+	"-name":       "estates.name DESC NULLS LAST",
+	"plot_count":  "estates.plot_count ASC",
+	"-plot_count": "estates.plot_count DESC",
+	"tree_count":  "tree_count ASC",
+	"-tree_count": "tree_count DESC",
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern so they are matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *Repository) GetEstates(ctx context.Context, filter EstateFilter) (estates []EstateSummary, total int64, err error) {
+	order, ok := estateSorts[filter.Sort]
+	if !ok {
+		err = fmt.Errorf("unknown estate sort %q", filter.Sort)
+		return
+	}
+
+	query := r.Db.WithContext(ctx).Model(&Estate{}).Scopes(ownEstates)
+	if filter.Name != nil {
+		query = query.Where("estates.name ILIKE ?", "%"+likeEscaper.Replace(*filter.Name)+"%")
+	}
+	if filter.MinPlotCount != nil {
+		query = query.Where("estates.plot_count >= ?", *filter.MinPlotCount)
+	}
+	if filter.MaxPlotCount != nil {
+		query = query.Where("estates.plot_count <= ?", *filter.MaxPlotCount)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("estates.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("estates.created_at < ?", filter.CreatedTo.AddDate(0, 0, 1))
+	}
+	query = query.Session(&gorm.Session{})
+
+	result := query.Count(&total)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	// The tree counts are aggregated along with the page of estates instead of one query per estate
+	result = query.Select("estates.*, COUNT(trees.id) AS tree_count").
+		Joins("LEFT JOIN trees ON trees.estate_id = estates.id AND trees.status = ?", TreeStatusAlive).
+		Group("estates.id").Order(order + ", estates.id ASC").
+		Limit(filter.Limit).Offset(filter.Offset).Find(&estates)
 	if result.Error != nil {
 		err = result.Error
 		return
@@ -30,35 +154,707 @@ func (r *Repository) GetEstateByID(ctx context.Context, estateID string) (estate
 	return
 }
 
-func (r *Repository) CreateTree(ctx context.Context, newTree *Tree) (err error) {
-	result := r.Db.WithContext(ctx).Create(newTree)
+// CreateTree plants a tree in an estate of the organisation of the context, only when plantable
+// says its plot can be planted in the estate or returning ErrTreeOutOfEstate. The estate is locked
+// to share while it is checked, so it can't be shrunk nor have its boundary changed until the tree
+// is saved, and its update then finds the tree.
+func (r *Repository) CreateTree(ctx context.Context, newTree *Tree, plantable func(estate Estate, x int, y int) bool) (err error) {
+	err = r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var estate Estate
+		result := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id", "length", "width", "boundary").
+			Where("id", newTree.EstateID).Scopes(ownEstates).First(&estate)
+		if result.Error != nil {
+			return result.Error
+		}
+		if !plantable(estate, newTree.HorizontalPosition, newTree.VerticalPosition) {
+			return ErrTreeOutOfEstate
+		}
+
+		result = tx.Create(newTree)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected < 1 {
+			return errors.New("Insert operation failed because rows affected is 0")
+		}
+
+		return adjustEstateStats(tx, []EstateStat{{EstateID: newTree.EstateID, Status: newTree.Status, Height: newTree.Height, TreeCount: 1}})
+	})
+
+	return
+}
+
+// adjustEstateStats adds changes to the tree counts of the materialised stats, it must run in
+// the transaction of the write to the trees.
+func adjustEstateStats(tx *gorm.DB, changes []EstateStat) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "estate_id"}, {Name: "status"}, {Name: "height"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"tree_count": gorm.Expr("estate_stats.tree_count + excluded.tree_count")}),
+	}).Create(&changes).Error
+}
+
+// estateStatsOf counts the trees by status and height, sign being 1 to add them to the stats
+// and -1 to take them out.
+func estateStatsOf(trees []Tree, sign int) (changes []EstateStat) {
+	indexes := map[EstateStat]int{}
+	for _, tree := range trees {
+		key := EstateStat{EstateID: tree.EstateID, Status: tree.Status, Height: tree.Height}
+		index, ok := indexes[key]
+		if !ok {
+			index = len(changes)
+			indexes[key] = index
+			changes = append(changes, key)
+		}
+		changes[index].TreeCount += sign
+	}
+
+	return
+}
+
+// aliveTrees leaves the felled and dead trees out of a trees query unless they are asked for.
+func aliveTrees(includeInactive bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if includeInactive {
+			return db
+		}
+		return db.Where("status", TreeStatusAlive)
+	}
+}
+
+// treesInBlock narrows a trees query of the estate of a block down to the trees planted in its plots.
+func treesInBlock(block Block) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("vertical_position BETWEEN ? AND ?", block.YFrom, block.YTo).
+			Where("horizontal_position BETWEEN ? AND ?", block.XFrom, block.XTo)
+	}
+}
+
+// treesOutsideBounds narrows a trees query down to the trees beyond a length and width.
+func treesOutsideBounds(length int, width int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("horizontal_position > ? OR vertical_position > ?", length, width)
+	}
+}
+
+// treesInsideBounds narrows a trees query down to the trees within a length and width.
+func treesInsideBounds(length int, width int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("horizontal_position <= ? AND vertical_position <= ?", length, width)
+	}
+}
+
+// UpdateEstate saves the details and the size of an estate, only when it is still at the version
+// it was read at and bumping it, or returns ErrVersionMismatch. When shrinking it leaves living
+// trees outside of its new bounds they are returned along with ErrTreesOutOfBounds, unless force
+// relocates them to the nearest free plot inside which is plantable or fells them. The living
+// trees inside of the new bounds but on plots which aren't plantable anymore are always returned
+// along with ErrTreesOutOfBounds, as they can't be moved onto the boundary in a sensible way. The
+// felled and dead trees are records of what stood there, which are kept where they were. The
+// blocks left outside of the new bounds are returned along with ErrBlocksOutOfBounds whatever the
+// force, as a block can't be shrunk along with the estate.
+func (r *Repository) UpdateEstate(ctx context.Context, estate Estate, force string, plantable func(x int, y int) bool) (outOfBounds []Tree, blocksOutOfBounds []BlockSummary, err error) {
+	err = r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the estate waits for the trees being planted in it, which lock it to share while
+		// their plot is checked against it, and holds off the next ones until the new bounds are saved
+		var stored Estate
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("length", "width", "boundary", "version").
+			Where("id", estate.ID).Scopes(ownEstates).First(&stored)
+		if result.Error != nil {
+			return result.Error
+		}
+		if stored.Version != estate.Version {
+			return ErrVersionMismatch
+		}
+
+		if estate.Length < stored.Length || estate.Width < stored.Width {
+			var outside []BlockSummary
+			result = tx.Model(&Block{}).Where("blocks.estate_id", estate.ID).
+				Where("blocks.x_to > ? OR blocks.y_to > ?", estate.Length, estate.Width).
+				Scopes(blockTreeCounts).Order("blocks.name ASC").Find(&outside)
+			if result.Error != nil {
+				return result.Error
+			}
+			if len(outside) > 0 {
+				blocksOutOfBounds = outside
+				return ErrBlocksOutOfBounds
+			}
+		}
+
+		resized := estate.Length != stored.Length || estate.Width != stored.Width
+		if estate.Boundary != nil && (resized || !slices.Equal(estate.Boundary, stored.Boundary)) {
+			outside, err := treesOutsidePlantable(tx, estate, plantable)
+			if err != nil {
+				return err
+			}
+			if len(outside) > 0 {
+				outOfBounds = outside
+				return ErrTreesOutOfBounds
+			}
+		}
+
+		result = tx.Where("estate_id", estate.ID).Scopes(inOwnEstates("estate_id"), aliveTrees(false), treesOutsideBounds(estate.Length, estate.Width)).
+			Order("vertical_position ASC, horizontal_position ASC").Find(&outOfBounds)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if len(outOfBounds) > 0 {
+			switch force {
+			case "":
+				return ErrTreesOutOfBounds
+			case ResizeForceRelocate:
+				var planted []Plot
+				result = tx.Model(&Tree{}).Select("horizontal_position", "vertical_position").Where("estate_id", estate.ID).
+					Scopes(inOwnEstates("estate_id"), aliveTrees(false), treesInsideBounds(estate.Length, estate.Width)).Find(&planted)
+				if result.Error != nil {
+					return result.Error
+				}
+
+				relocated, err := relocateTrees(outOfBounds, estate, planted, plantable)
+				if err != nil {
+					return err
+				}
+				for _, tree := range relocated {
+					result = tx.Model(&Tree{}).Where("id", tree.ID).Updates(map[string]interface{}{
+						"horizontal_position": tree.HorizontalPosition,
+						"vertical_position":   tree.VerticalPosition,
+						"version":             gorm.Expr("version + 1"),
+					})
+					if result.Error != nil {
+						return result.Error
+					}
+				}
+			case ResizeForceFell:
+				result = tx.Model(&Tree{}).Where("estate_id", estate.ID).Scopes(inOwnEstates("estate_id"), aliveTrees(false), treesOutsideBounds(estate.Length, estate.Width)).
+					Updates(map[string]interface{}{
+						"status":         TreeStatusFelled,
+						"removal_reason": ResizeRemovalReason,
+						"removed_at":     gorm.Expr("CURRENT_DATE"),
+						"version":        gorm.Expr("version + 1"),
+					})
+				if result.Error == nil {
+					felled := make([]Tree, 0, len(outOfBounds))
+					for _, tree := range outOfBounds {
+						tree.Status = TreeStatusFelled
+						felled = append(felled, tree)
+					}
+					result.Error = adjustEstateStats(tx, append(estateStatsOf(outOfBounds, -1), estateStatsOf(felled, 1)...))
+				}
+			default:
+				return fmt.Errorf("unknown resize force %q", force)
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		version := estate.Version
+		estate.Version++
+		result = tx.Model(&Estate{}).Where("id", estate.ID).Where("version", version).Scopes(ownEstates).
+			Select("name", "owner", "width", "length", "origin_latitude", "origin_longitude", "bearing", "boundary", "plot_count", "version").
+			Updates(&estate)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected < 1 {
+			return estateVersionMismatch(tx, estate.ID)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// treesOutsidePlantable returns the living trees within the bounds of an estate whose plot isn't
+// plantable. The trees are streamed rather than loaded at once, only the ones outside being kept.
+func treesOutsidePlantable(tx *gorm.DB, estate Estate, plantable func(x int, y int) bool) (outside []Tree, err error) {
+	rows, err := tx.Model(&Tree{}).Where("estate_id", estate.ID).Scopes(inOwnEstates("estate_id"), aliveTrees(false), treesInsideBounds(estate.Length, estate.Width)).
+		Order("vertical_position ASC, horizontal_position ASC").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tree Tree
+		err = tx.ScanRows(rows, &tree)
+		if err != nil {
+			return nil, err
+		}
+		if !plantable(tree.HorizontalPosition, tree.VerticalPosition) {
+			outside = append(outside, tree)
+		}
+	}
+
+	return outside, rows.Err()
+}
+
+// relocateTrees moves each of the trees outside of the bounds of an estate, in their order, to
+// the free plantable plot nearest to it. The plots are searched in rings around the plot of the
+// edge closest to the tree, the nearest plot of the first ring with a free one being taken, so
+// that two trees never end up on the same plot nor on a planted one. It returns ErrNoFreePlots
+// when there are fewer free plots than trees.
+func relocateTrees(trees []Tree, estate Estate, planted []Plot, plantable func(x int, y int) bool) ([]Tree, error) {
+	occupied := make(map[Plot]bool, len(planted)+len(trees))
+	for _, plot := range planted {
+		if plantable(plot.X, plot.Y) {
+			occupied[plot] = true
+		}
+	}
+	if estate.PlotCount-len(occupied) < len(trees) {
+		return nil, ErrNoFreePlots
+	}
+
+	relocated := make([]Tree, 0, len(trees))
+	for _, tree := range trees {
+		plot, ok := nearestFreePlot(tree, estate, occupied, plantable)
+		if !ok {
+			return nil, ErrNoFreePlots
+		}
+		occupied[plot] = true
+
+		tree.HorizontalPosition, tree.VerticalPosition = plot.X, plot.Y
+		relocated = append(relocated, tree)
+	}
+
+	return relocated, nil
+}
+
+// nearestFreePlot searches the rings of plots around the plot of the edge of an estate closest to
+// a tree, from the inside out, returning the plot of the first ring with a free one which is the
+// nearest to the tree, or false when every plot is taken.
+func nearestFreePlot(tree Tree, estate Estate, occupied map[Plot]bool, plantable func(x int, y int) bool) (Plot, bool) {
+	cx, cy := min(tree.HorizontalPosition, estate.Length), min(tree.VerticalPosition, estate.Width)
+
+	for ring := 0; ring <= max(estate.Length, estate.Width); ring++ {
+		var nearest Plot
+		nearestDistance := -1
+		for y := max(cy-ring, 1); y <= min(cy+ring, estate.Width); y++ {
+			// The rows in between only cross the ring on its two sides
+			step := 1
+			if y != cy-ring && y != cy+ring {
+				step = 2 * ring
+			}
+			for x := cx - ring; x <= cx+ring; x += step {
+				plot := Plot{X: x, Y: y}
+				if x < 1 || x > estate.Length || occupied[plot] || !plantable(x, y) {
+					continue
+				}
+
+				dx, dy := x-tree.HorizontalPosition, y-tree.VerticalPosition
+				if distance := dx*dx + dy*dy; nearestDistance < 0 || distance < nearestDistance {
+					nearest, nearestDistance = plot, distance
+				}
+			}
+		}
+		if nearestDistance >= 0 {
+			return nearest, true
+		}
+	}
+
+	return Plot{}, false
+}
+
+// estateVersionMismatch tells why an estate wasn't updated at a version, returning
+// gorm.ErrRecordNotFound when it doesn't exist anymore and ErrVersionMismatch otherwise.
+func estateVersionMismatch(tx *gorm.DB, estateID string) error {
+	err := findOwnEstate(tx, estateID)
+	if err != nil {
+		return err
+	}
+
+	return ErrVersionMismatch
+}
+
+// DeleteEstate soft deletes an estate, which hides it and so its trees from every read, only when
+// it is still at the version it was read at or returns ErrVersionMismatch.
+func (r *Repository) DeleteEstate(ctx context.Context, estateID string, version int) (err error) {
+	result := r.Db.WithContext(ctx).Where("id", estateID).Where("version", version).Scopes(ownEstates).Delete(&Estate{})
 	if result.Error != nil {
 		err = result.Error
 		return
 	}
 
 	if result.RowsAffected < 1 {
-		err = errors.New("Insert operation failed because rows affected is 0")
+		err = estateVersionMismatch(r.Db.WithContext(ctx), estateID)
 		return
 	}
 
 	return
 }
 
-func (r *Repository) GetTreeHeightsByEstateID(ctx context.Context, estateID string) (treeHeights []int, err error) {
-	result := r.Db.WithContext(ctx).Table("trees").Select("height").
-		Where("estate_id", estateID).Order("height asc").Find(&treeHeights)
+// RestoreEstate brings back an estate which was deleted after deletedAfter.
+func (r *Repository) RestoreEstate(ctx context.Context, estateID string, deletedAfter time.Time) (err error) {
+	result := r.Db.WithContext(ctx).Unscoped().Model(&Estate{}).
+		Where("id", estateID).Where("deleted_at > ?", deletedAfter).Scopes(ownEstates).
+		Update("deleted_at", nil)
 	if result.Error != nil {
 		err = result.Error
 		return
 	}
 
+	if result.RowsAffected < 1 {
+		err = gorm.ErrRecordNotFound
+		return
+	}
+
 	return
 }
 
-func (r *Repository) GetTreesByEstateIDAndPlotsLocations(ctx context.Context, estateID string) (trees []Tree, err error) {
-	result := r.Db.WithContext(ctx).Select("id", "horizontal_position", "vertical_position", "height").
-		Where("estate_id", estateID).Order("vertical_position ASC, horizontal_position ASC;").Find(&trees)
+// PurgeDeletedEstates hard deletes the estates of every organisation which were deleted before
+// deletedBefore, their trees and harvests are deleted along with them by the database.
+func (r *Repository) PurgeDeletedEstates(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	result := r.Db.WithContext(ctx).Unscoped().Where("deleted_at <= ?", deletedBefore).Delete(&Estate{})
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	purged = result.RowsAffected
+
+	return
+}
+
+// GetPlantedPlotCountByEstateID counts the plots of an estate which have at least one living tree.
+func (r *Repository) GetPlantedPlotCountByEstateID(ctx context.Context, estateID string) (count int64, err error) {
+	result := r.Db.WithContext(ctx).Table("trees").
+		Select("COUNT(DISTINCT (horizontal_position, vertical_position))").
+		Where("estate_id", estateID).Scopes(inOwnEstates("estate_id"), aliveTrees(false)).Scan(&count)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// GetVacantRunsByEstateID returns the runs of the plots of an estate without any living tree,
+// ordered by row then by x, among the runs of plots given, which must not overlap. The runs are
+// split around the planted plots by the database, so neither the plots nor the trees are loaded.
+func (r *Repository) GetVacantRunsByEstateID(ctx context.Context, estateID string, runs []PlotRun) (vacant []PlotRun, err error) {
+	if len(runs) == 0 {
+		return
+	}
+
+	ys, xFroms, xTos := make([]int64, len(runs)), make([]int64, len(runs)), make([]int64, len(runs))
+	for i, run := range runs {
+		ys[i], xFroms[i], xTos[i] = int64(run.Y), int64(run.XFrom), int64(run.XTo)
+	}
+	planted := r.Db.WithContext(ctx).Table("trees").Distinct("horizontal_position", "vertical_position").
+		Where("estate_id", estateID).Scopes(inOwnEstates("estate_id"), aliveTrees(false))
+
+	// Each run is cut at its planted plots, bounded by the plots just before and after it, the
+	// gaps between consecutive cuts being the vacant runs
+	result := r.Db.WithContext(ctx).Raw(`WITH runs AS (SELECT * FROM unnest(?::int[], ?::int[], ?::int[]) AS runs(y, x_from, x_to)), `+
+		`cuts AS (SELECT y, x_from, x_from - 1 AS x FROM runs `+
+		`UNION ALL SELECT runs.y, runs.x_from, planted.horizontal_position FROM runs JOIN (?) planted `+
+		`ON planted.vertical_position = runs.y AND planted.horizontal_position BETWEEN runs.x_from AND runs.x_to `+
+		`UNION ALL SELECT y, x_from, x_to + 1 FROM runs) `+
+		`SELECT y, x + 1 AS x_from, next_x - 1 AS x_to FROM (SELECT y, x, LEAD(x) OVER (PARTITION BY y, x_from ORDER BY x) AS next_x FROM cuts) gaps `+
+		`WHERE next_x - x > 1 ORDER BY 1, 2`, pq.Array(ys), pq.Array(xFroms), pq.Array(xTos), planted).Scan(&vacant)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetTreeByID(ctx context.Context, estateID string, treeID string) (tree Tree, err error) {
+	result := r.Db.WithContext(ctx).Where("id", treeID).Where("estate_id", estateID).Scopes(inOwnEstates("estate_id")).First(&tree)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// FellTree marks a living tree as felled or dead, only when it is still at the version it was read
+// at and bumping it. It returns ErrTreeNotAlive when the tree was already felled or died, and
+// ErrVersionMismatch when it was updated otherwise since it was read.
+func (r *Repository) FellTree(ctx context.Context, treeID string, version int, status string, reason string, removedAt time.Time) (err error) {
+	err = r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var felled Tree
+		result := tx.Model(&felled).Clauses(clause.Returning{Columns: []clause.Column{{Name: "estate_id"}, {Name: "height"}}}).
+			Where("id", treeID).Where("status", TreeStatusAlive).Where("version", version).Scopes(inOwnEstates("estate_id")).
+			Updates(map[string]interface{}{
+				"status":         status,
+				"removal_reason": reason,
+				"removed_at":     removedAt,
+				"version":        gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected < 1 {
+			var current Tree
+			result = tx.Select("status").Where("id", treeID).Scopes(inOwnEstates("estate_id")).First(&current)
+			if result.Error != nil {
+				return result.Error
+			}
+			if current.Status != TreeStatusAlive {
+				return ErrTreeNotAlive
+			}
+			return ErrVersionMismatch
+		}
+
+		return adjustEstateStats(tx, []EstateStat{
+			{EstateID: felled.EstateID, Status: TreeStatusAlive, Height: felled.Height, TreeCount: -1},
+			{EstateID: felled.EstateID, Status: status, Height: felled.Height, TreeCount: 1},
+		})
+	})
+
+	return
+}
+
+func (r *Repository) GetTreeLineageByID(ctx context.Context, estateID string, treeID string) (trees []Tree, err error) {
+	// A raw query isn't narrowed down by the scopes, the replanted trees being in the same estate
+	// only the first one is checked to be in a live estate of the organisation
+	organisationID, ok := OrganisationFromContext(ctx)
+	if !ok {
+		err = ErrNoOrganisation
+		return
+	}
+
+	result := r.Db.WithContext(ctx).Raw(`WITH RECURSIVE lineage AS (
+		SELECT trees.*, 0 AS generation FROM trees WHERE id = ? AND estate_id = ?
+			AND estate_id IN (SELECT id FROM estates WHERE organisation_id = ? AND estates.deleted_at IS NULL)
+		UNION ALL
+		SELECT trees.*, lineage.generation + 1 FROM trees JOIN lineage ON trees.id = lineage.previous_tree_id
+	) SELECT * FROM lineage ORDER BY generation`, treeID, estateID, organisationID).Scan(&trees)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// GetTreeHeightStatsByEstateID derives the height stats of an estate from its materialised
+// stats, which hold at most a row per status and height whatever the number of trees.
+func (r *Repository) GetTreeHeightStatsByEstateID(ctx context.Context, estateID string, percentiles []float64, bucketSize int, includeInactive bool) (stats TreeHeightStats, err error) {
+	var counts []EstateStat
+	result := r.Db.WithContext(ctx).Model(&EstateStat{}).Select("height, SUM(tree_count) AS tree_count").
+		Where("estate_id", estateID).Scopes(inOwnEstates("estate_id"), aliveTrees(includeInactive)).
+		Group("height").Having("SUM(tree_count) > 0").Order("height").Find(&counts)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return heightCountsToStats(counts, percentiles, bucketSize), nil
+}
+
+// heightCountsToStats derives the height stats from the tree counts by height, ordered by
+// height. The percentiles are interpolated the same as percentile_cont does.
+func heightCountsToStats(counts []EstateStat, percentiles []float64, bucketSize int) (stats TreeHeightStats) {
+	var sum float64
+	for _, count := range counts {
+		stats.Count += count.TreeCount
+		sum += float64(count.Height * count.TreeCount)
+	}
+	if stats.Count == 0 {
+		return
+	}
+
+	stats.MinHeight = counts[0].Height
+	stats.MaxHeight = counts[len(counts)-1].Height
+	stats.MeanHeight = sum / float64(stats.Count)
+
+	var squares float64
+	for _, count := range counts {
+		deviation := float64(count.Height) - stats.MeanHeight
+		squares += deviation * deviation * float64(count.TreeCount)
+	}
+	stats.StddevHeight = math.Sqrt(squares / float64(stats.Count))
+
+	// nth returns the height of the nth tree once ordered by height, from 0
+	nth := func(n int) float64 {
+		for _, count := range counts {
+			if n < count.TreeCount {
+				return float64(count.Height)
+			}
+			n -= count.TreeCount
+		}
+		return float64(stats.MaxHeight)
+	}
+	percentile := func(fraction float64) float64 {
+		rank := fraction * float64(stats.Count-1)
+		lower := math.Floor(rank)
+		return nth(int(lower)) + (rank-lower)*(nth(int(math.Ceil(rank)))-nth(int(lower)))
+	}
+
+	stats.MedianHeight = percentile(0.5)
+	if len(percentiles) > 0 {
+		stats.Percentiles = make(HeightPercentiles, 0, len(percentiles))
+		for _, fraction := range percentiles {
+			stats.Percentiles = append(stats.Percentiles, percentile(fraction))
+		}
+	}
+
+	for _, count := range counts {
+		bucket := count.Height / bucketSize
+		if len(stats.Histogram) == 0 || stats.Histogram[len(stats.Histogram)-1].Bucket != bucket {
+			stats.Histogram = append(stats.Histogram, TreeHeightBucket{Bucket: bucket})
+		}
+		stats.Histogram[len(stats.Histogram)-1].Count += count.TreeCount
+	}
+
+	return
+}
+
+// RecomputeEstateStats rebuilds the materialised stats of an estate from its trees, or of
+// every estate of every organisation when estateID is nil. The trees can't be written to in
+// the meantime.
+func (r *Repository) RecomputeEstateStats(ctx context.Context, estateID *string) (err error) {
+	err = r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("LOCK TABLE trees IN SHARE MODE")
+		if result.Error != nil {
+			return result.Error
+		}
+
+		stats := tx.Session(&gorm.Session{AllowGlobalUpdate: true})
+		counts := tx.Table("trees").Select("estate_id, status, height, COUNT(*)").Group("estate_id, status, height")
+		if estateID != nil {
+			stats = stats.Where("estate_id", *estateID)
+			counts = counts.Where("estate_id", *estateID)
+		}
+
+		result = stats.Delete(&EstateStat{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return tx.Exec("INSERT INTO estate_stats (estate_id, status, height, tree_count) ?", counts).Error
+	})
+
+	return
+}
+
+// GetEstateStatsDrift compares the materialised stats of an estate, or of every estate of every
+// organisation when estateID is nil, with its trees and returns the tree counts which don't match.
+func (r *Repository) GetEstateStatsDrift(ctx context.Context, estateID *string) (drift []EstateStatDrift, err error) {
+	stats := r.Db.Model(&EstateStat{}).Select("estate_id, status, height, tree_count").Where("tree_count <> 0")
+	counts := r.Db.Table("trees").Select("estate_id, status, height, COUNT(*) AS tree_count").Group("estate_id, status, height")
+	if estateID != nil {
+		stats = stats.Where("estate_id", *estateID)
+		counts = counts.Where("estate_id", *estateID)
+	}
+
+	result := r.Db.WithContext(ctx).Raw(`SELECT COALESCE(s.estate_id, t.estate_id) AS estate_id, COALESCE(s.status, t.status) AS status, `+
+		`COALESCE(s.height, t.height) AS height, COALESCE(s.tree_count, 0) AS tree_count, COALESCE(t.tree_count, 0) AS actual_tree_count `+
+		`FROM (?) s FULL JOIN (?) t ON s.estate_id = t.estate_id AND s.status = t.status AND s.height = t.height `+
+		`WHERE COALESCE(s.tree_count, 0) <> COALESCE(t.tree_count, 0) ORDER BY 1, 2, 3`, stats, counts).Scan(&drift)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// SnapshotEstateStats records the stats of every estate of every organisation on a day from their
+// materialised stats.
+// The estates which already have a snapshot of the day are skipped, so it can be run any number
+// of times a day, and it returns how many snapshots were taken.
+func (r *Repository) SnapshotEstateStats(ctx context.Context, day time.Time) (taken int64, err error) {
+	missing := r.Db.WithContext(ctx).Model(&Estate{}).Where("NOT EXISTS (?)",
+		r.Db.Model(&EstateStatSnapshot{}).Select("1").
+			Where("estate_stat_snapshots.estate_id = estates.id").Where("estate_stat_snapshots.snapshot_date", day))
+
+	var estateIDs []string
+	result := missing.Session(&gorm.Session{}).Order("estates.id").Pluck("estates.id", &estateIDs)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+	if len(estateIDs) == 0 {
+		return
+	}
+
+	var counts []EstateStat
+	result = r.Db.WithContext(ctx).Where("estate_id IN (?)", missing.Session(&gorm.Session{}).Select("estates.id")).
+		Where("tree_count <> 0").Order("estate_id, status, height").Find(&counts)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	taking := make([]EstateStatSnapshot, 0, len(estateIDs))
+	indexes := make(map[string]int, len(estateIDs))
+	for _, estateID := range estateIDs {
+		indexes[estateID] = len(taking)
+		taking = append(taking, EstateStatSnapshot{EstateID: estateID, SnapshotDate: day})
+	}
+
+	alive := map[string][]EstateStat{}
+	for _, count := range counts {
+		index, ok := indexes[count.EstateID]
+		if !ok {
+			// The estate was created since they were listed, its snapshot is left to the next run
+			continue
+		}
+		switch count.Status {
+		case TreeStatusAlive:
+			alive[count.EstateID] = append(alive[count.EstateID], count)
+		case TreeStatusFelled:
+			taking[index].FelledCount += count.TreeCount
+		case TreeStatusDead:
+			taking[index].DeadCount += count.TreeCount
+		}
+	}
+
+	for i := range taking {
+		stats := heightCountsToStats(alive[taking[i].EstateID], nil, 1)
+		taking[i].TreeCount = stats.Count
+		taking[i].MinHeight = stats.MinHeight
+		taking[i].MaxHeight = stats.MaxHeight
+		taking[i].MeanHeight = stats.MeanHeight
+		taking[i].StddevHeight = stats.StddevHeight
+		taking[i].MedianHeight = stats.MedianHeight
+	}
+
+	// Another server may have taken some of the snapshots in the meantime
+	result = r.Db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&taking, 1000)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+	taken = result.RowsAffected
+
+	return
+}
+
+// statsIntervalUnits maps an interval of the stats history to the unit its periods start on.
+var statsIntervalUnits = map[string]string{
+	StatsIntervalDay:   "day",
+	StatsIntervalWeek:  "week",
+	StatsIntervalMonth: "month",
+}
+
+// GetEstateStatSnapshots returns the snapshots of an estate taken from one day to another, both
+// included, one per interval in chronological order. The stats being a state of the estate rather
+// than a flow, each interval is represented by its last snapshot.
+func (r *Repository) GetEstateStatSnapshots(ctx context.Context, estateID string, from time.Time, to time.Time, interval string) (snapshots []EstateStatSnapshot, err error) {
+	unit, ok := statsIntervalUnits[interval]
+	if !ok {
+		err = fmt.Errorf("unknown stats interval %q", interval)
+		return
+	}
+
+	period := "DATE_TRUNC('" + unit + "', snapshot_date)"
+	result := r.Db.WithContext(ctx).Select("DISTINCT ON ("+period+") *, "+period+"::date AS period").
+		Where("estate_id", estateID).Where("snapshot_date >= ?", from).Where("snapshot_date <= ?", to).
+		Scopes(inOwnEstates("estate_id")).Order(period + ", snapshot_date DESC").Find(&snapshots)
 	if result.Error != nil {
 		err = result.Error
 		return
@@ -66,3 +862,563 @@ func (r *Repository) GetTreesByEstateIDAndPlotsLocations(ctx context.Context, es
 
 	return
 }
+
+// GetPortfolioStats returns the estates matching the filter, oldest first, along with the height
+// stats of their trees, and the height stats of the trees of all of them. Both are derived from
+// the materialised stats, so it doesn't go through the trees.
+func (r *Repository) GetPortfolioStats(ctx context.Context, filter PortfolioFilter, percentiles []float64, bucketSize int, includeInactive bool) (estates []PortfolioEstate, stats TreeHeightStats, err error) {
+	query := r.Db.WithContext(ctx).Model(&Estate{}).Scopes(ownEstates)
+	if filter.Name != nil {
+		query = query.Where("estates.name ILIKE ?", "%"+likeEscaper.Replace(*filter.Name)+"%")
+	}
+	if filter.Owner != nil {
+		query = query.Where("estates.owner ILIKE ?", "%"+likeEscaper.Replace(*filter.Owner)+"%")
+	}
+	query = query.Session(&gorm.Session{})
+
+	var found []Estate
+	result := query.Order("estates.created_at ASC, estates.id ASC").Find(&found)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+	if len(found) == 0 {
+		return
+	}
+
+	var counts []EstateStat
+	result = r.Db.WithContext(ctx).Model(&EstateStat{}).Select("estate_id, height, SUM(tree_count) AS tree_count").
+		Where("estate_id IN (?)", query.Select("estates.id")).Scopes(aliveTrees(includeInactive)).
+		Group("estate_id, height").Having("SUM(tree_count) > 0").Order("estate_id, height").Find(&counts)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	byEstate := map[string][]EstateStat{}
+	byHeight := map[int]int{}
+	for _, count := range counts {
+		byEstate[count.EstateID] = append(byEstate[count.EstateID], count)
+		byHeight[count.Height] += count.TreeCount
+	}
+
+	estates = make([]PortfolioEstate, 0, len(found))
+	for _, estate := range found {
+		estates = append(estates, PortfolioEstate{
+			Estate: estate,
+			Stats:  heightCountsToStats(byEstate[estate.ID], percentiles, bucketSize),
+		})
+	}
+
+	total := make([]EstateStat, 0, len(byHeight))
+	for height, treeCount := range byHeight {
+		total = append(total, EstateStat{Height: height, TreeCount: treeCount})
+	}
+	slices.SortFunc(total, func(a, b EstateStat) int {
+		return a.Height - b.Height
+	})
+	stats = heightCountsToStats(total, percentiles, bucketSize)
+
+	return
+}
+
+// getTreeHeightStats aggregates the heights of the trees of a scope, the percentiles being
+// fractions from 0 to 1.
+func (r *Repository) getTreeHeightStats(ctx context.Context, percentiles []float64, bucketSize int, scope func(db *gorm.DB) *gorm.DB) (stats TreeHeightStats, err error) {
+	// Every aggregate but the count is null without any tree
+	columns := "COUNT(*) AS count, COALESCE(MIN(height), 0) AS min_height, COALESCE(MAX(height), 0) AS max_height, " +
+		"COALESCE(AVG(height), 0) AS mean_height, COALESCE(STDDEV_POP(height), 0) AS stddev_height, " +
+		"COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY height), 0) AS median_height"
+	var args []any
+	if len(percentiles) > 0 {
+		// gorm would wrap a slice in parentheses, which makes a row out of the array elements
+		placeholders := make([]string, 0, len(percentiles))
+		for _, percentile := range percentiles {
+			placeholders = append(placeholders, "?")
+			args = append(args, percentile)
+		}
+		columns += ", array_to_json(percentile_cont(ARRAY[" + strings.Join(placeholders, ", ") + "]::float8[]) WITHIN GROUP (ORDER BY height)) AS percentiles"
+	}
+
+	result := r.Db.WithContext(ctx).Table("trees").Select(columns, args...).Scopes(scope).Scan(&stats)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	if stats.Count == 0 {
+		return
+	}
+
+	result = r.Db.WithContext(ctx).Table("trees").Select("height / ? AS bucket, COUNT(*) AS count", bucketSize).
+		Scopes(scope).Group("bucket").Order("bucket").Scan(&stats.Histogram)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetTreesByEstateIDAndPlotsLocations(ctx context.Context, estateID string, includeInactive bool) (trees []Tree, err error) {
+	result := r.Db.WithContext(ctx).Select("id", "horizontal_position", "vertical_position", "height").
+		Where("estate_id", estateID).Scopes(inOwnEstates("estate_id"), aliveTrees(includeInactive)).Order("vertical_position ASC, horizontal_position ASC;").Find(&trees)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetTreesByEstateID(ctx context.Context, estateID string, filter TreeFilter) (trees []Tree, total int64, err error) {
+	query := r.Db.WithContext(ctx).Model(&Tree{}).Where("estate_id", estateID).Scopes(inOwnEstates("estate_id"))
+	if filter.Species != nil {
+		query = query.Where("species", *filter.Species)
+	}
+	if filter.Variety != nil {
+		query = query.Where("variety", *filter.Variety)
+	}
+	if filter.HealthStatus != nil {
+		query = query.Where("health_status", *filter.HealthStatus)
+	}
+	if filter.Block != nil {
+		query = query.Scopes(treesInBlock(*filter.Block))
+	}
+	query = query.Scopes(aliveTrees(filter.IncludeInactive)).Session(&gorm.Session{})
+
+	result := query.Count(&total)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	result = query.Order("vertical_position ASC, horizontal_position ASC").
+		Limit(filter.Limit).Offset(filter.Offset).Find(&trees)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// treeBreakdownColumns maps a breakdown to the columns its groups are keyed by,
+// a variety only makes sense within its species.
+var treeBreakdownColumns = map[string]string{
+	TreeBreakdownSpecies:      "species",
+	TreeBreakdownVariety:      "species, variety",
+	TreeBreakdownHealthStatus: "health_status",
+}
+
+func (r *Repository) GetTreeHeightBreakdownByEstateID(ctx context.Context, estateID string, breakdown string, includeInactive bool) (groups []TreeGroupStats, err error) {
+	columns, ok := treeBreakdownColumns[breakdown]
+	if !ok {
+		err = fmt.Errorf("unknown tree breakdown %q", breakdown)
+		return
+	}
+
+	result := r.Db.WithContext(ctx).Table("trees").
+		Select(columns+", COUNT(*) AS count, MIN(height) AS min_height, MAX(height) AS max_height, "+
+			"percentile_cont(0.5) WITHIN GROUP (ORDER BY height) AS median_height").
+		Where("estate_id", estateID).Scopes(inOwnEstates("estate_id"), aliveTrees(includeInactive)).Group(columns).Order(columns).Find(&groups)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetTreesNearPosition(ctx context.Context, estateID string, x int, y int, radius int, metric string, includeInactive bool) (trees []NearbyTree, err error) {
+	// The bounding square of the radius is looked up through the position index first
+	query := r.Db.WithContext(ctx).Model(&Tree{}).Where("estate_id", estateID).
+		Where("vertical_position BETWEEN ? AND ?", y-radius, y+radius).
+		Where("horizontal_position BETWEEN ? AND ?", x-radius, x+radius).
+		Scopes(inOwnEstates("estate_id"), aliveTrees(includeInactive))
+
+	switch metric {
+	case DistanceMetricChebyshev:
+		query = query.Select("trees.*, GREATEST(ABS(horizontal_position - ?), ABS(vertical_position - ?)) AS distance", x, y)
+	case DistanceMetricEuclidean:
+		query = query.Select("trees.*, SQRT(POWER(horizontal_position - ?, 2) + POWER(vertical_position - ?, 2)) AS distance", x, y).
+			Where("POWER(horizontal_position - ?, 2) + POWER(vertical_position - ?, 2) <= ?", x, y, radius*radius)
+	default:
+		err = fmt.Errorf("unknown distance metric %q", metric)
+		return
+	}
+
+	result := query.Order("distance ASC, vertical_position ASC, horizontal_position ASC").Find(&trees)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetTreeGridByEstateID(ctx context.Context, estateID string, cell int, includeInactive bool) (cells []GridCell, err error) {
+	result := r.Db.WithContext(ctx).Table("trees").
+		Select("(horizontal_position - 1) / ? AS cell_x, (vertical_position - 1) / ? AS cell_y, COUNT(*) AS tree_count, "+
+			"COUNT(DISTINCT (horizontal_position, vertical_position)) AS planted_plots, AVG(height) AS avg_height, MAX(height) AS max_height", cell, cell).
+		Where("estate_id", estateID).Scopes(inOwnEstates("estate_id"), aliveTrees(includeInactive)).
+		Group("cell_x, cell_y").Order("cell_y ASC, cell_x ASC").Find(&cells)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) CreateHarvest(ctx context.Context, newHarvest *Harvest) (err error) {
+	err = findOwnEstate(r.Db.WithContext(ctx), newHarvest.EstateID)
+	if err != nil {
+		return
+	}
+
+	result := r.Db.WithContext(ctx).Create(newHarvest)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	if result.RowsAffected < 1 {
+		err = errors.New("Insert operation failed because rows affected is 0")
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetHarvestsByTreeID(ctx context.Context, treeID string) (harvests []Harvest, err error) {
+	result := r.Db.WithContext(ctx).Where("tree_id", treeID).Scopes(inOwnEstates("estate_id")).Order("harvested_on DESC").Find(&harvests)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// yieldGroupColumns maps a yield grouping to the column its groups are keyed by, and what it is selected as.
+var yieldGroupColumns = map[string]struct{ column, alias string }{
+	YieldGroupMonth: {column: "DATE_TRUNC('month', harvests.harvested_on)::date", alias: "month"},
+	YieldGroupRow:   {column: "trees.vertical_position", alias: "y"},
+}
+
+// GetYieldByEstateID sums up the harvests of an estate, in a single row when groupBy is empty.
+func (r *Repository) GetYieldByEstateID(ctx context.Context, estateID string, groupBy string, filter YieldFilter) (yields []Yield, err error) {
+	query := r.Db.WithContext(ctx).Table("harvests").
+		Joins("JOIN trees ON trees.id = harvests.tree_id").
+		Where("harvests.estate_id", estateID).Scopes(inOwnEstates("harvests.estate_id"))
+	if filter.From != nil {
+		query = query.Where("harvests.harvested_on >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("harvests.harvested_on <= ?", *filter.To)
+	}
+	if filter.YFrom != nil {
+		query = query.Where("trees.vertical_position >= ?", *filter.YFrom)
+	}
+	if filter.YTo != nil {
+		query = query.Where("trees.vertical_position <= ?", *filter.YTo)
+	}
+
+	aggregates := "COUNT(*) AS harvest_count, COUNT(DISTINCT harvests.tree_id) AS tree_count, " +
+		"COALESCE(SUM(harvests.bunch_count), 0) AS bunch_count, COALESCE(SUM(harvests.weight_kg), 0) AS weight_kg"
+
+	if groupBy == "" {
+		query = query.Select(aggregates)
+	} else {
+		group, ok := yieldGroupColumns[groupBy]
+		if !ok {
+			err = fmt.Errorf("unknown yield grouping %q", groupBy)
+			return
+		}
+		query = query.Select(group.column + " AS " + group.alias + ", " + aggregates).Group(group.alias).Order(group.alias)
+	}
+
+	result := query.Find(&yields)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// profileAxisColumns maps a profile axis to the position column its lines are grouped by.
+var profileAxisColumns = map[string]string{
+	ProfileAxisRow:    "vertical_position",
+	ProfileAxisColumn: "horizontal_position",
+}
+
+// GetHeightProfileByEstateID returns the height stats of the trees of each row or column of an
+// estate, depending on the axis, in order. The rows or columns without any tree are left out.
+func (r *Repository) GetHeightProfileByEstateID(ctx context.Context, estateID string, axis string, includeInactive bool) (lines []HeightProfileLine, err error) {
+	column, ok := profileAxisColumns[axis]
+	if !ok {
+		err = fmt.Errorf("unknown profile axis %q", axis)
+		return
+	}
+
+	result := r.Db.WithContext(ctx).Table("trees").
+		Select(column+" AS position, COUNT(*) AS tree_count, MIN(height) AS min_height, MAX(height) AS max_height, AVG(height) AS mean_height").
+		Where("estate_id", estateID).Scopes(inOwnEstates("estate_id"), aliveTrees(includeInactive)).
+		Group(column).Order(column).Find(&lines)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) CreateDivision(ctx context.Context, newDivision *Division) (err error) {
+	err = findOwnEstate(r.Db.WithContext(ctx), newDivision.EstateID)
+	if err != nil {
+		return
+	}
+
+	result := r.Db.WithContext(ctx).Create(newDivision)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	if result.RowsAffected < 1 {
+		err = errors.New("Insert operation failed because rows affected is 0")
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetDivisionByID(ctx context.Context, estateID string, divisionID string) (division Division, err error) {
+	result := r.Db.WithContext(ctx).Where("id", divisionID).Where("estate_id", estateID).Scopes(inOwnEstates("estate_id")).First(&division)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetDivisionsByEstateID(ctx context.Context, estateID string) (divisions []Division, err error) {
+	result := r.Db.WithContext(ctx).Where("estate_id", estateID).Scopes(inOwnEstates("estate_id")).Order("name ASC").Find(&divisions)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// CreateBlock saves a block unless it overlaps another block of the estate, in which case
+// ErrBlockOverlaps is returned.
+func (r *Repository) CreateBlock(ctx context.Context, newBlock *Block) (err error) {
+	err = r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Blocks created at the same time in the estate are checked one after the other, and
+		// against the size it has once its resizes are saved
+		var estate Estate
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "length", "width").Where("id", newBlock.EstateID).Scopes(ownEstates).First(&estate)
+		if result.Error != nil {
+			return result.Error
+		}
+		if newBlock.XTo > estate.Length || newBlock.YTo > estate.Width {
+			return ErrBlockOutOfEstate
+		}
+
+		var overlapping int64
+		result = tx.Model(&Block{}).Where("estate_id", newBlock.EstateID).
+			Where("x_from <= ? AND x_to >= ?", newBlock.XTo, newBlock.XFrom).
+			Where("y_from <= ? AND y_to >= ?", newBlock.YTo, newBlock.YFrom).
+			Count(&overlapping)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if overlapping > 0 {
+			return ErrBlockOverlaps
+		}
+
+		result = tx.Create(newBlock)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected < 1 {
+			return errors.New("Insert operation failed because rows affected is 0")
+		}
+
+		return nil
+	})
+
+	return
+}
+
+func (r *Repository) GetBlockByID(ctx context.Context, estateID string, blockID string) (block Block, err error) {
+	result := r.Db.WithContext(ctx).Where("id", blockID).Where("estate_id", estateID).Scopes(inOwnEstates("estate_id")).First(&block)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// blockTreeCounts selects the blocks of a blocks query along with their count of living trees.
+func blockTreeCounts(db *gorm.DB) *gorm.DB {
+	return db.Select("blocks.*, COUNT(trees.id) AS tree_count").
+		Joins("LEFT JOIN trees ON trees.estate_id = blocks.estate_id AND trees.status = ? "+
+			"AND trees.vertical_position BETWEEN blocks.y_from AND blocks.y_to "+
+			"AND trees.horizontal_position BETWEEN blocks.x_from AND blocks.x_to", TreeStatusAlive).
+		Group("blocks.id")
+}
+
+func (r *Repository) GetBlocksByEstateID(ctx context.Context, estateID string, divisionID *string) (blocks []BlockSummary, err error) {
+	query := r.Db.WithContext(ctx).Model(&Block{}).Where("blocks.estate_id", estateID).Scopes(inOwnEstates("blocks.estate_id"))
+	if divisionID != nil {
+		query = query.Where("blocks.division_id", *divisionID)
+	}
+
+	result := query.Scopes(blockTreeCounts).Order("blocks.name ASC").Find(&blocks)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+func (r *Repository) DeleteBlock(ctx context.Context, estateID string, blockID string) (err error) {
+	result := r.Db.WithContext(ctx).Where("id", blockID).Where("estate_id", estateID).Scopes(inOwnEstates("estate_id")).Delete(&Block{})
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	if result.RowsAffected < 1 {
+		err = gorm.ErrRecordNotFound
+		return
+	}
+
+	return
+}
+
+func (r *Repository) GetTreeHeightStatsByBlock(ctx context.Context, block Block, percentiles []float64, bucketSize int, includeInactive bool) (stats TreeHeightStats, err error) {
+	return r.getTreeHeightStats(ctx, percentiles, bucketSize, func(db *gorm.DB) *gorm.DB {
+		return db.Where("estate_id", block.EstateID).Scopes(inOwnEstates("estate_id"), treesInBlock(block), aliveTrees(includeInactive))
+	})
+}
+
+func (r *Repository) GetTreesByBlockAndPlotsLocations(ctx context.Context, block Block, includeInactive bool) (trees []Tree, err error) {
+	result := r.Db.WithContext(ctx).Select("id", "horizontal_position", "vertical_position", "height").
+		Where("estate_id", block.EstateID).Scopes(inOwnEstates("estate_id"), treesInBlock(block), aliveTrees(includeInactive)).Order("vertical_position ASC, horizontal_position ASC").Find(&trees)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// ownIdempotencyKey narrows an idempotency keys query down to a key of the organisation of its
+// context, failing it without one.
+func ownIdempotencyKey(key string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		organisationID, ok := OrganisationFromContext(db.Statement.Context)
+		if !ok {
+			db.AddError(ErrNoOrganisation)
+			return db
+		}
+
+		return db.Where("organisation_id", organisationID).Where("idempotency_key", key)
+	}
+}
+
+// ClaimIdempotencyKey saves an idempotency key of the organisation of the context for a request
+// about to be processed, unless it is already held by another request and hasn't expired.
+// It returns whether the key was claimed, only one of the requests sent at once with a key
+// claiming it.
+func (r *Repository) ClaimIdempotencyKey(ctx context.Context, idempotencyKey *IdempotencyKey) (claimed bool, err error) {
+	organisationID, ok := OrganisationFromContext(ctx)
+	if !ok {
+		err = ErrNoOrganisation
+		return
+	}
+	idempotencyKey.OrganisationID = organisationID
+
+	// An expired key is claimed over again as if it had never been used
+	result := r.Db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "organisation_id"}, {Name: "idempotency_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"request_hash", "status_code", "content_type", "response_body", "created_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "idempotency_keys.expires_at <= NOW()"}}},
+	}).Create(idempotencyKey)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	claimed = result.RowsAffected > 0
+
+	return
+}
+
+// GetIdempotencyKey returns an idempotency key of the organisation of the context which hasn't
+// expired.
+func (r *Repository) GetIdempotencyKey(ctx context.Context, key string) (idempotencyKey IdempotencyKey, err error) {
+	result := r.Db.WithContext(ctx).Scopes(ownIdempotencyKey(key)).Where("expires_at > NOW()").First(&idempotencyKey)
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// SaveIdempotencyResponse saves the response to the request holding an idempotency key of the
+// organisation of the context, to replay it when the request is retried.
+func (r *Repository) SaveIdempotencyResponse(ctx context.Context, key string, statusCode int, contentType string, body []byte) (err error) {
+	result := r.Db.WithContext(ctx).Model(&IdempotencyKey{}).Scopes(ownIdempotencyKey(key)).Where("status_code IS NULL").
+		Updates(map[string]interface{}{"status_code": statusCode, "content_type": contentType, "response_body": body})
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	if result.RowsAffected < 1 {
+		err = gorm.ErrRecordNotFound
+		return
+	}
+
+	return
+}
+
+// ReleaseIdempotencyKey deletes an idempotency key of the organisation of the context held by a
+// request which failed without a response worth replaying, so that it can be retried.
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, key string) (err error) {
+	result := r.Db.WithContext(ctx).Scopes(ownIdempotencyKey(key)).Where("status_code IS NULL").Delete(&IdempotencyKey{})
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	return
+}
+
+// PurgeExpiredIdempotencyKeys deletes the idempotency keys of every organisation which expired
+// before expiredBefore.
+func (r *Repository) PurgeExpiredIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (purged int64, err error) {
+	result := r.Db.WithContext(ctx).Where("expires_at <= ?", expiredBefore).Delete(&IdempotencyKey{})
+	if result.Error != nil {
+		err = result.Error
+		return
+	}
+
+	purged = result.RowsAffected
+
+	return
+}
@@ -6,12 +6,54 @@ package repository
 
 import (
 	"context"
+	"time"
 )
 
+// RepositoryInterface is scoped to the organisation of the context, see WithOrganisation, but
+// for the organisations themselves and the jobs over every estate: PurgeDeletedEstates,
+// RecomputeEstateStats, GetEstateStatsDrift, SnapshotEstateStats and PurgeExpiredIdempotencyKeys.
 type RepositoryInterface interface {
+	CreateOrganisation(ctx context.Context, newOrganisation *Organisation) (err error)
 	CreateEstate(ctx context.Context, newEstate *Estate) (err error)
 	GetEstateByID(ctx context.Context, estateID string) (estate Estate, err error)
-	CreateTree(ctx context.Context, newTree *Tree) (err error)
-	GetTreeHeightsByEstateID(ctx context.Context, estateID string) (treeHeights []int, err error)
-	GetTreesByEstateIDAndPlotsLocations(ctx context.Context, estateID string) (trees []Tree, err error)
+	GetEstates(ctx context.Context, filter EstateFilter) (estates []EstateSummary, total int64, err error)
+	UpdateEstate(ctx context.Context, estate Estate, force string, plantable func(x int, y int) bool) (outOfBounds []Tree, blocksOutOfBounds []BlockSummary, err error)
+	DeleteEstate(ctx context.Context, estateID string, version int) (err error)
+	RestoreEstate(ctx context.Context, estateID string, deletedAfter time.Time) (err error)
+	PurgeDeletedEstates(ctx context.Context, deletedBefore time.Time) (purged int64, err error)
+	GetPlantedPlotCountByEstateID(ctx context.Context, estateID string) (count int64, err error)
+	GetVacantRunsByEstateID(ctx context.Context, estateID string, runs []PlotRun) (vacant []PlotRun, err error)
+	CreateTree(ctx context.Context, newTree *Tree, plantable func(estate Estate, x int, y int) bool) (err error)
+	GetTreeByID(ctx context.Context, estateID string, treeID string) (tree Tree, err error)
+	FellTree(ctx context.Context, treeID string, version int, status string, reason string, removedAt time.Time) (err error)
+	GetTreeLineageByID(ctx context.Context, estateID string, treeID string) (trees []Tree, err error)
+	GetTreeHeightStatsByEstateID(ctx context.Context, estateID string, percentiles []float64, bucketSize int, includeInactive bool) (stats TreeHeightStats, err error)
+	RecomputeEstateStats(ctx context.Context, estateID *string) (err error)
+	GetEstateStatsDrift(ctx context.Context, estateID *string) (drift []EstateStatDrift, err error)
+	GetPortfolioStats(ctx context.Context, filter PortfolioFilter, percentiles []float64, bucketSize int, includeInactive bool) (estates []PortfolioEstate, stats TreeHeightStats, err error)
+	SnapshotEstateStats(ctx context.Context, day time.Time) (taken int64, err error)
+	GetEstateStatSnapshots(ctx context.Context, estateID string, from time.Time, to time.Time, interval string) (snapshots []EstateStatSnapshot, err error)
+	GetTreesByEstateIDAndPlotsLocations(ctx context.Context, estateID string, includeInactive bool) (trees []Tree, err error)
+	GetTreesByEstateID(ctx context.Context, estateID string, filter TreeFilter) (trees []Tree, total int64, err error)
+	GetTreesNearPosition(ctx context.Context, estateID string, x int, y int, radius int, metric string, includeInactive bool) (trees []NearbyTree, err error)
+	GetTreeGridByEstateID(ctx context.Context, estateID string, cell int, includeInactive bool) (cells []GridCell, err error)
+	GetTreeHeightBreakdownByEstateID(ctx context.Context, estateID string, breakdown string, includeInactive bool) (groups []TreeGroupStats, err error)
+	CreateHarvest(ctx context.Context, newHarvest *Harvest) (err error)
+	GetHarvestsByTreeID(ctx context.Context, treeID string) (harvests []Harvest, err error)
+	GetYieldByEstateID(ctx context.Context, estateID string, groupBy string, filter YieldFilter) (yields []Yield, err error)
+	GetHeightProfileByEstateID(ctx context.Context, estateID string, axis string, includeInactive bool) (lines []HeightProfileLine, err error)
+	CreateDivision(ctx context.Context, newDivision *Division) (err error)
+	GetDivisionByID(ctx context.Context, estateID string, divisionID string) (division Division, err error)
+	GetDivisionsByEstateID(ctx context.Context, estateID string) (divisions []Division, err error)
+	CreateBlock(ctx context.Context, newBlock *Block) (err error)
+	GetBlockByID(ctx context.Context, estateID string, blockID string) (block Block, err error)
+	GetBlocksByEstateID(ctx context.Context, estateID string, divisionID *string) (blocks []BlockSummary, err error)
+	DeleteBlock(ctx context.Context, estateID string, blockID string) (err error)
+	GetTreeHeightStatsByBlock(ctx context.Context, block Block, percentiles []float64, bucketSize int, includeInactive bool) (stats TreeHeightStats, err error)
+	GetTreesByBlockAndPlotsLocations(ctx context.Context, block Block, includeInactive bool) (trees []Tree, err error)
+	ClaimIdempotencyKey(ctx context.Context, idempotencyKey *IdempotencyKey) (claimed bool, err error)
+	GetIdempotencyKey(ctx context.Context, key string) (idempotencyKey IdempotencyKey, err error)
+	SaveIdempotencyResponse(ctx context.Context, key string, statusCode int, contentType string, body []byte) (err error)
+	ReleaseIdempotencyKey(ctx context.Context, key string) (err error)
+	PurgeExpiredIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (purged int64, err error)
 }
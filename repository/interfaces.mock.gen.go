@@ -12,6 +12,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +40,49 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// ClaimIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) ClaimIdempotencyKey(ctx context.Context, idempotencyKey *IdempotencyKey) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimIdempotencyKey", ctx, idempotencyKey)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimIdempotencyKey indicates an expected call of ClaimIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) ClaimIdempotencyKey(ctx, idempotencyKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).ClaimIdempotencyKey), ctx, idempotencyKey)
+}
+
+// CreateBlock mocks base method.
+func (m *MockRepositoryInterface) CreateBlock(ctx context.Context, newBlock *Block) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBlock", ctx, newBlock)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBlock indicates an expected call of CreateBlock.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateBlock(ctx, newBlock any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBlock", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateBlock), ctx, newBlock)
+}
+
+// CreateDivision mocks base method.
+func (m *MockRepositoryInterface) CreateDivision(ctx context.Context, newDivision *Division) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDivision", ctx, newDivision)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDivision indicates an expected call of CreateDivision.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateDivision(ctx, newDivision any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDivision", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateDivision), ctx, newDivision)
+}
+
 // CreateEstate mocks base method.
 func (m *MockRepositoryInterface) CreateEstate(ctx context.Context, newEstate *Estate) error {
 	m.ctrl.T.Helper()
@@ -53,18 +97,148 @@ func (mr *MockRepositoryInterfaceMockRecorder) CreateEstate(ctx, newEstate any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEstate", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateEstate), ctx, newEstate)
 }
 
+// CreateHarvest mocks base method.
+func (m *MockRepositoryInterface) CreateHarvest(ctx context.Context, newHarvest *Harvest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHarvest", ctx, newHarvest)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateHarvest indicates an expected call of CreateHarvest.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateHarvest(ctx, newHarvest any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHarvest", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateHarvest), ctx, newHarvest)
+}
+
+// CreateOrganisation mocks base method.
+func (m *MockRepositoryInterface) CreateOrganisation(ctx context.Context, newOrganisation *Organisation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrganisation", ctx, newOrganisation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOrganisation indicates an expected call of CreateOrganisation.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOrganisation(ctx, newOrganisation any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrganisation", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOrganisation), ctx, newOrganisation)
+}
+
 // CreateTree mocks base method.
-func (m *MockRepositoryInterface) CreateTree(ctx context.Context, newTree *Tree) error {
+func (m *MockRepositoryInterface) CreateTree(ctx context.Context, newTree *Tree, plantable func(Estate, int, int) bool) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTree", ctx, newTree)
+	ret := m.ctrl.Call(m, "CreateTree", ctx, newTree, plantable)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateTree indicates an expected call of CreateTree.
-func (mr *MockRepositoryInterfaceMockRecorder) CreateTree(ctx, newTree any) *gomock.Call {
+func (mr *MockRepositoryInterfaceMockRecorder) CreateTree(ctx, newTree, plantable any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateTree), ctx, newTree, plantable)
+}
+
+// DeleteBlock mocks base method.
+func (m *MockRepositoryInterface) DeleteBlock(ctx context.Context, estateID, blockID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBlock", ctx, estateID, blockID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBlock indicates an expected call of DeleteBlock.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteBlock(ctx, estateID, blockID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBlock", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteBlock), ctx, estateID, blockID)
+}
+
+// DeleteEstate mocks base method.
+func (m *MockRepositoryInterface) DeleteEstate(ctx context.Context, estateID string, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteEstate", ctx, estateID, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteEstate indicates an expected call of DeleteEstate.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteEstate(ctx, estateID, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEstate", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteEstate), ctx, estateID, version)
+}
+
+// FellTree mocks base method.
+func (m *MockRepositoryInterface) FellTree(ctx context.Context, treeID string, version int, status, reason string, removedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FellTree", ctx, treeID, version, status, reason, removedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FellTree indicates an expected call of FellTree.
+func (mr *MockRepositoryInterfaceMockRecorder) FellTree(ctx, treeID, version, status, reason, removedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FellTree", reflect.TypeOf((*MockRepositoryInterface)(nil).FellTree), ctx, treeID, version, status, reason, removedAt)
+}
+
+// GetBlockByID mocks base method.
+func (m *MockRepositoryInterface) GetBlockByID(ctx context.Context, estateID, blockID string) (Block, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockByID", ctx, estateID, blockID)
+	ret0, _ := ret[0].(Block)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockByID indicates an expected call of GetBlockByID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetBlockByID(ctx, estateID, blockID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockByID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetBlockByID), ctx, estateID, blockID)
+}
+
+// GetBlocksByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetBlocksByEstateID(ctx context.Context, estateID string, divisionID *string) ([]BlockSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlocksByEstateID", ctx, estateID, divisionID)
+	ret0, _ := ret[0].([]BlockSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlocksByEstateID indicates an expected call of GetBlocksByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetBlocksByEstateID(ctx, estateID, divisionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlocksByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetBlocksByEstateID), ctx, estateID, divisionID)
+}
+
+// GetDivisionByID mocks base method.
+func (m *MockRepositoryInterface) GetDivisionByID(ctx context.Context, estateID, divisionID string) (Division, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDivisionByID", ctx, estateID, divisionID)
+	ret0, _ := ret[0].(Division)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDivisionByID indicates an expected call of GetDivisionByID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetDivisionByID(ctx, estateID, divisionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDivisionByID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetDivisionByID), ctx, estateID, divisionID)
+}
+
+// GetDivisionsByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetDivisionsByEstateID(ctx context.Context, estateID string) ([]Division, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDivisionsByEstateID", ctx, estateID)
+	ret0, _ := ret[0].([]Division)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDivisionsByEstateID indicates an expected call of GetDivisionsByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetDivisionsByEstateID(ctx, estateID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateTree), ctx, newTree)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDivisionsByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetDivisionsByEstateID), ctx, estateID)
 }
 
 // GetEstateByID mocks base method.
@@ -82,32 +256,422 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetEstateByID(ctx, estateID any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEstateByID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetEstateByID), ctx, estateID)
 }
 
-// GetTreeHeightsByEstateID mocks base method.
-func (m *MockRepositoryInterface) GetTreeHeightsByEstateID(ctx context.Context, estateID string) ([]int, error) {
+// GetEstateStatSnapshots mocks base method.
+func (m *MockRepositoryInterface) GetEstateStatSnapshots(ctx context.Context, estateID string, from, to time.Time, interval string) ([]EstateStatSnapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEstateStatSnapshots", ctx, estateID, from, to, interval)
+	ret0, _ := ret[0].([]EstateStatSnapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEstateStatSnapshots indicates an expected call of GetEstateStatSnapshots.
+func (mr *MockRepositoryInterfaceMockRecorder) GetEstateStatSnapshots(ctx, estateID, from, to, interval any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEstateStatSnapshots", reflect.TypeOf((*MockRepositoryInterface)(nil).GetEstateStatSnapshots), ctx, estateID, from, to, interval)
+}
+
+// GetEstateStatsDrift mocks base method.
+func (m *MockRepositoryInterface) GetEstateStatsDrift(ctx context.Context, estateID *string) ([]EstateStatDrift, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEstateStatsDrift", ctx, estateID)
+	ret0, _ := ret[0].([]EstateStatDrift)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEstateStatsDrift indicates an expected call of GetEstateStatsDrift.
+func (mr *MockRepositoryInterfaceMockRecorder) GetEstateStatsDrift(ctx, estateID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEstateStatsDrift", reflect.TypeOf((*MockRepositoryInterface)(nil).GetEstateStatsDrift), ctx, estateID)
+}
+
+// GetEstates mocks base method.
+func (m *MockRepositoryInterface) GetEstates(ctx context.Context, filter EstateFilter) ([]EstateSummary, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEstates", ctx, filter)
+	ret0, _ := ret[0].([]EstateSummary)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetEstates indicates an expected call of GetEstates.
+func (mr *MockRepositoryInterfaceMockRecorder) GetEstates(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEstates", reflect.TypeOf((*MockRepositoryInterface)(nil).GetEstates), ctx, filter)
+}
+
+// GetHarvestsByTreeID mocks base method.
+func (m *MockRepositoryInterface) GetHarvestsByTreeID(ctx context.Context, treeID string) ([]Harvest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHarvestsByTreeID", ctx, treeID)
+	ret0, _ := ret[0].([]Harvest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHarvestsByTreeID indicates an expected call of GetHarvestsByTreeID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetHarvestsByTreeID(ctx, treeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHarvestsByTreeID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetHarvestsByTreeID), ctx, treeID)
+}
+
+// GetHeightProfileByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetHeightProfileByEstateID(ctx context.Context, estateID, axis string, includeInactive bool) ([]HeightProfileLine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeightProfileByEstateID", ctx, estateID, axis, includeInactive)
+	ret0, _ := ret[0].([]HeightProfileLine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHeightProfileByEstateID indicates an expected call of GetHeightProfileByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetHeightProfileByEstateID(ctx, estateID, axis, includeInactive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeightProfileByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetHeightProfileByEstateID), ctx, estateID, axis, includeInactive)
+}
+
+// GetIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) GetIdempotencyKey(ctx context.Context, key string) (IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdempotencyKey", ctx, key)
+	ret0, _ := ret[0].(IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdempotencyKey indicates an expected call of GetIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) GetIdempotencyKey(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).GetIdempotencyKey), ctx, key)
+}
+
+// GetPlantedPlotCountByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetPlantedPlotCountByEstateID(ctx context.Context, estateID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlantedPlotCountByEstateID", ctx, estateID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPlantedPlotCountByEstateID indicates an expected call of GetPlantedPlotCountByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPlantedPlotCountByEstateID(ctx, estateID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlantedPlotCountByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPlantedPlotCountByEstateID), ctx, estateID)
+}
+
+// GetPortfolioStats mocks base method.
+func (m *MockRepositoryInterface) GetPortfolioStats(ctx context.Context, filter PortfolioFilter, percentiles []float64, bucketSize int, includeInactive bool) ([]PortfolioEstate, TreeHeightStats, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTreeHeightsByEstateID", ctx, estateID)
-	ret0, _ := ret[0].([]int)
+	ret := m.ctrl.Call(m, "GetPortfolioStats", ctx, filter, percentiles, bucketSize, includeInactive)
+	ret0, _ := ret[0].([]PortfolioEstate)
+	ret1, _ := ret[1].(TreeHeightStats)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetPortfolioStats indicates an expected call of GetPortfolioStats.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPortfolioStats(ctx, filter, percentiles, bucketSize, includeInactive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPortfolioStats", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPortfolioStats), ctx, filter, percentiles, bucketSize, includeInactive)
+}
+
+// GetTreeByID mocks base method.
+func (m *MockRepositoryInterface) GetTreeByID(ctx context.Context, estateID, treeID string) (Tree, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreeByID", ctx, estateID, treeID)
+	ret0, _ := ret[0].(Tree)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeByID indicates an expected call of GetTreeByID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreeByID(ctx, estateID, treeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeByID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreeByID), ctx, estateID, treeID)
+}
+
+// GetTreeGridByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetTreeGridByEstateID(ctx context.Context, estateID string, cell int, includeInactive bool) ([]GridCell, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreeGridByEstateID", ctx, estateID, cell, includeInactive)
+	ret0, _ := ret[0].([]GridCell)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeGridByEstateID indicates an expected call of GetTreeGridByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreeGridByEstateID(ctx, estateID, cell, includeInactive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeGridByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreeGridByEstateID), ctx, estateID, cell, includeInactive)
+}
+
+// GetTreeHeightBreakdownByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetTreeHeightBreakdownByEstateID(ctx context.Context, estateID, breakdown string, includeInactive bool) ([]TreeGroupStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreeHeightBreakdownByEstateID", ctx, estateID, breakdown, includeInactive)
+	ret0, _ := ret[0].([]TreeGroupStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeHeightBreakdownByEstateID indicates an expected call of GetTreeHeightBreakdownByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreeHeightBreakdownByEstateID(ctx, estateID, breakdown, includeInactive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeHeightBreakdownByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreeHeightBreakdownByEstateID), ctx, estateID, breakdown, includeInactive)
+}
+
+// GetTreeHeightStatsByBlock mocks base method.
+func (m *MockRepositoryInterface) GetTreeHeightStatsByBlock(ctx context.Context, block Block, percentiles []float64, bucketSize int, includeInactive bool) (TreeHeightStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreeHeightStatsByBlock", ctx, block, percentiles, bucketSize, includeInactive)
+	ret0, _ := ret[0].(TreeHeightStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeHeightStatsByBlock indicates an expected call of GetTreeHeightStatsByBlock.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreeHeightStatsByBlock(ctx, block, percentiles, bucketSize, includeInactive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeHeightStatsByBlock", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreeHeightStatsByBlock), ctx, block, percentiles, bucketSize, includeInactive)
+}
+
+// GetTreeHeightStatsByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetTreeHeightStatsByEstateID(ctx context.Context, estateID string, percentiles []float64, bucketSize int, includeInactive bool) (TreeHeightStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreeHeightStatsByEstateID", ctx, estateID, percentiles, bucketSize, includeInactive)
+	ret0, _ := ret[0].(TreeHeightStats)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTreeHeightsByEstateID indicates an expected call of GetTreeHeightsByEstateID.
-func (mr *MockRepositoryInterfaceMockRecorder) GetTreeHeightsByEstateID(ctx, estateID any) *gomock.Call {
+// GetTreeHeightStatsByEstateID indicates an expected call of GetTreeHeightStatsByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreeHeightStatsByEstateID(ctx, estateID, percentiles, bucketSize, includeInactive any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeHeightsByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreeHeightsByEstateID), ctx, estateID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeHeightStatsByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreeHeightStatsByEstateID), ctx, estateID, percentiles, bucketSize, includeInactive)
+}
+
+// GetTreeLineageByID mocks base method.
+func (m *MockRepositoryInterface) GetTreeLineageByID(ctx context.Context, estateID, treeID string) ([]Tree, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreeLineageByID", ctx, estateID, treeID)
+	ret0, _ := ret[0].([]Tree)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreeLineageByID indicates an expected call of GetTreeLineageByID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreeLineageByID(ctx, estateID, treeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeLineageByID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreeLineageByID), ctx, estateID, treeID)
+}
+
+// GetTreesByBlockAndPlotsLocations mocks base method.
+func (m *MockRepositoryInterface) GetTreesByBlockAndPlotsLocations(ctx context.Context, block Block, includeInactive bool) ([]Tree, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreesByBlockAndPlotsLocations", ctx, block, includeInactive)
+	ret0, _ := ret[0].([]Tree)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreesByBlockAndPlotsLocations indicates an expected call of GetTreesByBlockAndPlotsLocations.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreesByBlockAndPlotsLocations(ctx, block, includeInactive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreesByBlockAndPlotsLocations", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreesByBlockAndPlotsLocations), ctx, block, includeInactive)
+}
+
+// GetTreesByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetTreesByEstateID(ctx context.Context, estateID string, filter TreeFilter) ([]Tree, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreesByEstateID", ctx, estateID, filter)
+	ret0, _ := ret[0].([]Tree)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetTreesByEstateID indicates an expected call of GetTreesByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreesByEstateID(ctx, estateID, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreesByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreesByEstateID), ctx, estateID, filter)
 }
 
 // GetTreesByEstateIDAndPlotsLocations mocks base method.
-func (m *MockRepositoryInterface) GetTreesByEstateIDAndPlotsLocations(ctx context.Context, estateID string) ([]Tree, error) {
+func (m *MockRepositoryInterface) GetTreesByEstateIDAndPlotsLocations(ctx context.Context, estateID string, includeInactive bool) ([]Tree, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTreesByEstateIDAndPlotsLocations", ctx, estateID)
+	ret := m.ctrl.Call(m, "GetTreesByEstateIDAndPlotsLocations", ctx, estateID, includeInactive)
 	ret0, _ := ret[0].([]Tree)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTreesByEstateIDAndPlotsLocations indicates an expected call of GetTreesByEstateIDAndPlotsLocations.
-func (mr *MockRepositoryInterfaceMockRecorder) GetTreesByEstateIDAndPlotsLocations(ctx, estateID any) *gomock.Call {
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreesByEstateIDAndPlotsLocations(ctx, estateID, includeInactive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreesByEstateIDAndPlotsLocations", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreesByEstateIDAndPlotsLocations), ctx, estateID, includeInactive)
+}
+
+// GetTreesNearPosition mocks base method.
+func (m *MockRepositoryInterface) GetTreesNearPosition(ctx context.Context, estateID string, x, y, radius int, metric string, includeInactive bool) ([]NearbyTree, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTreesNearPosition", ctx, estateID, x, y, radius, metric, includeInactive)
+	ret0, _ := ret[0].([]NearbyTree)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTreesNearPosition indicates an expected call of GetTreesNearPosition.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTreesNearPosition(ctx, estateID, x, y, radius, metric, includeInactive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreesNearPosition", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreesNearPosition), ctx, estateID, x, y, radius, metric, includeInactive)
+}
+
+// GetVacantRunsByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetVacantRunsByEstateID(ctx context.Context, estateID string, runs []PlotRun) ([]PlotRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVacantRunsByEstateID", ctx, estateID, runs)
+	ret0, _ := ret[0].([]PlotRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVacantRunsByEstateID indicates an expected call of GetVacantRunsByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetVacantRunsByEstateID(ctx, estateID, runs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVacantRunsByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetVacantRunsByEstateID), ctx, estateID, runs)
+}
+
+// GetYieldByEstateID mocks base method.
+func (m *MockRepositoryInterface) GetYieldByEstateID(ctx context.Context, estateID, groupBy string, filter YieldFilter) ([]Yield, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetYieldByEstateID", ctx, estateID, groupBy, filter)
+	ret0, _ := ret[0].([]Yield)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetYieldByEstateID indicates an expected call of GetYieldByEstateID.
+func (mr *MockRepositoryInterfaceMockRecorder) GetYieldByEstateID(ctx, estateID, groupBy, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetYieldByEstateID", reflect.TypeOf((*MockRepositoryInterface)(nil).GetYieldByEstateID), ctx, estateID, groupBy, filter)
+}
+
+// PurgeDeletedEstates mocks base method.
+func (m *MockRepositoryInterface) PurgeDeletedEstates(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedEstates", ctx, deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedEstates indicates an expected call of PurgeDeletedEstates.
+func (mr *MockRepositoryInterfaceMockRecorder) PurgeDeletedEstates(ctx, deletedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedEstates", reflect.TypeOf((*MockRepositoryInterface)(nil).PurgeDeletedEstates), ctx, deletedBefore)
+}
+
+// PurgeExpiredIdempotencyKeys mocks base method.
+func (m *MockRepositoryInterface) PurgeExpiredIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpiredIdempotencyKeys", ctx, expiredBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpiredIdempotencyKeys indicates an expected call of PurgeExpiredIdempotencyKeys.
+func (mr *MockRepositoryInterfaceMockRecorder) PurgeExpiredIdempotencyKeys(ctx, expiredBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpiredIdempotencyKeys", reflect.TypeOf((*MockRepositoryInterface)(nil).PurgeExpiredIdempotencyKeys), ctx, expiredBefore)
+}
+
+// RecomputeEstateStats mocks base method.
+func (m *MockRepositoryInterface) RecomputeEstateStats(ctx context.Context, estateID *string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecomputeEstateStats", ctx, estateID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecomputeEstateStats indicates an expected call of RecomputeEstateStats.
+func (mr *MockRepositoryInterfaceMockRecorder) RecomputeEstateStats(ctx, estateID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecomputeEstateStats", reflect.TypeOf((*MockRepositoryInterface)(nil).RecomputeEstateStats), ctx, estateID)
+}
+
+// ReleaseIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIdempotencyKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIdempotencyKey indicates an expected call of ReleaseIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) ReleaseIdempotencyKey(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).ReleaseIdempotencyKey), ctx, key)
+}
+
+// RestoreEstate mocks base method.
+func (m *MockRepositoryInterface) RestoreEstate(ctx context.Context, estateID string, deletedAfter time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreEstate", ctx, estateID, deletedAfter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreEstate indicates an expected call of RestoreEstate.
+func (mr *MockRepositoryInterfaceMockRecorder) RestoreEstate(ctx, estateID, deletedAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreEstate", reflect.TypeOf((*MockRepositoryInterface)(nil).RestoreEstate), ctx, estateID, deletedAfter)
+}
+
+// SaveIdempotencyResponse mocks base method.
+func (m *MockRepositoryInterface) SaveIdempotencyResponse(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveIdempotencyResponse", ctx, key, statusCode, contentType, body)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveIdempotencyResponse indicates an expected call of SaveIdempotencyResponse.
+func (mr *MockRepositoryInterfaceMockRecorder) SaveIdempotencyResponse(ctx, key, statusCode, contentType, body any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveIdempotencyResponse", reflect.TypeOf((*MockRepositoryInterface)(nil).SaveIdempotencyResponse), ctx, key, statusCode, contentType, body)
+}
+
+// SnapshotEstateStats mocks base method.
+func (m *MockRepositoryInterface) SnapshotEstateStats(ctx context.Context, day time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SnapshotEstateStats", ctx, day)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SnapshotEstateStats indicates an expected call of SnapshotEstateStats.
+func (mr *MockRepositoryInterfaceMockRecorder) SnapshotEstateStats(ctx, day any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SnapshotEstateStats", reflect.TypeOf((*MockRepositoryInterface)(nil).SnapshotEstateStats), ctx, day)
+}
+
+// UpdateEstate mocks base method.
+func (m *MockRepositoryInterface) UpdateEstate(ctx context.Context, estate Estate, force string, plantable func(int, int) bool) ([]Tree, []BlockSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEstate", ctx, estate, force, plantable)
+	ret0, _ := ret[0].([]Tree)
+	ret1, _ := ret[1].([]BlockSummary)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UpdateEstate indicates an expected call of UpdateEstate.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateEstate(ctx, estate, force, plantable any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreesByEstateIDAndPlotsLocations", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreesByEstateIDAndPlotsLocations), ctx, estateID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEstate", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateEstate), ctx, estate, force, plantable)
 }
@@ -2,10 +2,37 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	_ "github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrTreeNotAlive is returned when a tree which has already been felled or died is felled again.
+	ErrTreeNotAlive = errors.New("tree is not alive")
+	// ErrTreeOutOfEstate is returned when a tree is planted on a plot outside of the area or the
+	// boundary of its estate, as it is when the tree is saved.
+	ErrTreeOutOfEstate = errors.New("tree is out of the estate")
+	// ErrTreesOutOfBounds is returned when an estate is shrunk without saying what to do with the trees outside of it.
+	ErrTreesOutOfBounds = errors.New("trees are out of the estate's bounds")
+	// ErrNoFreePlots is returned when an estate is shrunk relocating the trees outside of it, without
+	// as many free plots left inside as there are trees to relocate.
+	ErrNoFreePlots = errors.New("not enough free plots to relocate the trees to")
+	// ErrBlocksOutOfBounds is returned when an estate is shrunk leaving some of its blocks outside of it.
+	ErrBlocksOutOfBounds = errors.New("blocks are out of the estate's bounds")
+	// ErrBlockOutOfEstate is returned when a block is created past the area of its estate.
+	ErrBlockOutOfEstate = errors.New("block is out of the estate")
+	// ErrBlockOverlaps is returned when a block is created over the plots of another block of the estate.
+	ErrBlockOverlaps = errors.New("block overlaps another block")
+	// ErrNoOrganisation is returned when the estates are read or written to without the organisation
+	// they belong to in the context.
+	ErrNoOrganisation = errors.New("no organisation in the context")
+	// ErrVersionMismatch is returned when an estate or a tree is updated at another version than
+	// its current one, someone else having updated it since it was read.
+	ErrVersionMismatch = errors.New("version does not match")
+)
+
 type Repository struct {
 	Db *gorm.DB
 }
@@ -15,3 +42,17 @@ func NewRepository(opts Repository) *Repository {
 		Db: opts.Db,
 	}
 }
+
+type organisationKey struct{}
+
+// WithOrganisation returns a context scoping the repository to the estates of an organisation,
+// an organisation never seeing the estates of another one or anything in them.
+func WithOrganisation(ctx context.Context, organisationID string) context.Context {
+	return context.WithValue(ctx, organisationKey{}, organisationID)
+}
+
+// OrganisationFromContext returns the organisation the repository is scoped to in a context.
+func OrganisationFromContext(ctx context.Context) (organisationID string, ok bool) {
+	organisationID, ok = ctx.Value(organisationKey{}).(string)
+	return organisationID, ok && organisationID != ""
+}
@@ -1,22 +1,403 @@
 // This file contains types that are used in the repository layer.
 package repository
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
 
-type Estate struct {
+// Health statuses a tree can be recorded with.
+const (
+	HealthStatusHealthy      = "healthy"
+	HealthStatusStressed     = "stressed"
+	HealthStatusDiseased     = "diseased"
+	HealthStatusPestInfested = "pest_infested"
+)
+
+// Lifecycle statuses of a tree, felled and dead trees are kept for their history.
+const (
+	TreeStatusAlive  = "alive"
+	TreeStatusFelled = "felled"
+	TreeStatusDead   = "dead"
+)
+
+// Metrics the distance between two plots can be measured with.
+const (
+	DistanceMetricEuclidean = "euclidean"
+	DistanceMetricChebyshev = "chebyshev"
+)
+
+// Columns the tree stats can be broken down by.
+const (
+	TreeBreakdownSpecies      = "species"
+	TreeBreakdownVariety      = "variety"
+	TreeBreakdownHealthStatus = "health_status"
+)
+
+// What to do with the trees left outside of an estate when it is shrunk. The trees asked to be
+// deleted are felled, keeping their harvests and lineage the same as felling a tree.
+const (
+	ResizeForceRelocate = "relocate"
+	ResizeForceFell     = "delete"
+)
+
+// ResizeRemovalReason is the removal reason of the trees felled by shrinking their estate.
+const ResizeRemovalReason = "Estate resized"
+
+// Groupings the harvested yield of an estate can be broken down by.
+const (
+	YieldGroupMonth = "month"
+	YieldGroupRow   = "row"
+)
+
+// Axes the height profile of an estate can be taken along, a row profile having a line per row.
+const (
+	ProfileAxisRow    = "row"
+	ProfileAxisColumn = "column"
+)
+
+// Intervals the stats history of an estate can be returned at.
+const (
+	StatsIntervalDay   = "day"
+	StatsIntervalWeek  = "week"
+	StatsIntervalMonth = "month"
+)
+
+// Organisation is a company using the service, it only ever sees its own estates.
+type Organisation struct {
 	ID        string    `gorm:"column:id;type:uuid;default:uuid_generate_v4();primaryKey"`
-	Width     int       `gorm:"column:width;not null"`
-	Length    int       `gorm:"column:length;not null"`
+	Name      string    `gorm:"column:name;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+type Estate struct {
+	ID string `gorm:"column:id;type:uuid;default:uuid_generate_v4();primaryKey"`
+	// OrganisationID is the organisation the estate belongs to, set from the context it is created with.
+	OrganisationID string  `gorm:"column:organisation_id;type:uuid;not null"`
+	Name           *string `gorm:"column:name"`
+	Owner          *string `gorm:"column:owner"`
+	Width          int     `gorm:"column:width;not null"`
+	Length         int     `gorm:"column:length;not null"`
+	// OriginLatitude and OriginLongitude are the WGS84 position of the outer corner of plot (1, 1),
+	// they are either both set or both nil.
+	OriginLatitude  *float64 `gorm:"column:origin_latitude"`
+	OriginLongitude *float64 `gorm:"column:origin_longitude"`
+	// Bearing is the direction of the x axis of the estate, in degrees clockwise from true north.
+	Bearing float64 `gorm:"column:bearing;not null"`
+	// Boundary is the outline of an irregular estate, nil when the whole rectangle is planted.
+	Boundary Boundary `gorm:"column:boundary;type:jsonb"`
+	// PlotCount is the number of plots which can be planted, inside of the boundary when there is
+	// one. It is set by the handlers along with the size and the boundary.
+	PlotCount int `gorm:"column:plot_count;not null"`
+	// Version is bumped on every update, which is only made to the version it was read at.
+	Version   int       `gorm:"column:version;default:1;not null"`
 	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
 	UpdatedAt time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+	// DeletedAt soft deletes the estate, its trees are left in place until it is purged.
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at"`
+}
+
+// BoundaryVertex is a corner of an estate boundary, measured in plots from the outer corner
+// of plot (1, 1), so the plot (x, y) spans from x-1 to x and from y-1 to y.
+type BoundaryVertex struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Boundary is the polygon outlining an estate, stored as a JSON array of its vertices.
+type Boundary []BoundaryVertex
+
+func (b Boundary) Value() (driver.Value, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func (b *Boundary) Scan(value any) error {
+	switch value := value.(type) {
+	case nil:
+		*b = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(value, b)
+	case string:
+		return json.Unmarshal([]byte(value), b)
+	default:
+		return fmt.Errorf("unsupported boundary type %T", value)
+	}
+}
+
+// EstateFilter narrows down and orders the estates returned by an estate listing.
+// Nil fields are not filtered on.
+type EstateFilter struct {
+	Name         *string
+	MinPlotCount *int
+	MaxPlotCount *int
+	CreatedFrom  *time.Time
+	// CreatedTo is inclusive of the whole day.
+	CreatedTo *time.Time
+	// Sort is a field from estateSorts, prefixed with - for descending order.
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+// EstateSummary is an estate along with its count of living trees.
+type EstateSummary struct {
+	Estate    `gorm:"embedded"`
+	TreeCount int `gorm:"column:tree_count"`
 }
 
 type Tree struct {
-	ID                 string    `gorm:"column:id;type:uuid;default:uuid_generate_v4();primaryKey"`
-	EstateID           string    `gorm:"column:estate_id;type:uuid;not null"`
-	HorizontalPosition int       `gorm:"column:horizontal_position;not null"`
-	VerticalPosition   int       `gorm:"column:vertical_position;not null"`
-	Height             int       `gorm:"column:height;not null"`
-	CreatedAt          time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
-	UpdatedAt          time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+	ID                 string     `gorm:"column:id;type:uuid;default:uuid_generate_v4();primaryKey"`
+	EstateID           string     `gorm:"column:estate_id;type:uuid;not null"`
+	HorizontalPosition int        `gorm:"column:horizontal_position;not null"`
+	VerticalPosition   int        `gorm:"column:vertical_position;not null"`
+	Height             int        `gorm:"column:height;not null"`
+	Species            *string    `gorm:"column:species"`
+	Variety            *string    `gorm:"column:variety"`
+	PlantingDate       *time.Time `gorm:"column:planting_date;type:date"`
+	HealthStatus       string     `gorm:"column:health_status;default:healthy;not null"`
+	Status             string     `gorm:"column:status;default:alive;not null"`
+	RemovalReason      *string    `gorm:"column:removal_reason"`
+	RemovedAt          *time.Time `gorm:"column:removed_at;type:date"`
+	PreviousTreeID     *string    `gorm:"column:previous_tree_id;type:uuid"`
+	Version            int        `gorm:"column:version;default:1;not null"`
+	CreatedAt          time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt          time.Time  `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// TreeFilter narrows down the trees returned by a tree listing.
+// Nil fields are not filtered on.
+type TreeFilter struct {
+	Species      *string
+	Variety      *string
+	HealthStatus *string
+	// Block only returns the trees planted in the plots of the block.
+	Block *Block
+	// IncludeInactive also returns the felled and dead trees.
+	IncludeInactive bool
+	Limit           int
+	Offset          int
+}
+
+// TreeGroupStats is the height stats of one group of trees, the group keys
+// which are not part of the breakdown are left nil.
+type TreeGroupStats struct {
+	Species      *string `gorm:"column:species"`
+	Variety      *string `gorm:"column:variety"`
+	HealthStatus *string `gorm:"column:health_status"`
+	Count        int     `gorm:"column:count"`
+	MinHeight    int     `gorm:"column:min_height"`
+	MaxHeight    int     `gorm:"column:max_height"`
+	MedianHeight float64 `gorm:"column:median_height"`
+}
+
+// TreeHeightStats is the height stats of a set of trees, aggregated by the database.
+// Percentiles follow the order of the requested percentiles, and the histogram is left
+// out of the aggregate query and filled by a query of its own.
+type TreeHeightStats struct {
+	Count        int                `gorm:"column:count"`
+	MinHeight    int                `gorm:"column:min_height"`
+	MaxHeight    int                `gorm:"column:max_height"`
+	MeanHeight   float64            `gorm:"column:mean_height"`
+	StddevHeight float64            `gorm:"column:stddev_height"`
+	MedianHeight float64            `gorm:"column:median_height"`
+	Percentiles  HeightPercentiles  `gorm:"column:percentiles;type:jsonb"`
+	Histogram    []TreeHeightBucket `gorm:"-"`
+}
+
+// EstateStat is the number of trees of an estate with a status and a height, the row of the
+// materialised stats. As a change to the stats TreeCount is the number of trees to add.
+type EstateStat struct {
+	EstateID  string `gorm:"column:estate_id;primaryKey"`
+	Status    string `gorm:"column:status;primaryKey"`
+	Height    int    `gorm:"column:height;primaryKey"`
+	TreeCount int    `gorm:"column:tree_count"`
+}
+
+// EstateStatDrift is a tree count of the materialised stats which doesn't match the trees.
+type EstateStatDrift struct {
+	EstateStat
+	ActualTreeCount int `gorm:"column:actual_tree_count"`
+}
+
+// EstateStatSnapshot is the stats of an estate on a day, the height stats being those of its
+// living trees. Period is the first day of the interval the snapshot was returned for.
+type EstateStatSnapshot struct {
+	EstateID     string    `gorm:"column:estate_id;primaryKey"`
+	SnapshotDate time.Time `gorm:"column:snapshot_date;type:date;primaryKey"`
+	Period       time.Time `gorm:"column:period;->"`
+	TreeCount    int       `gorm:"column:tree_count"`
+	FelledCount  int       `gorm:"column:felled_count"`
+	DeadCount    int       `gorm:"column:dead_count"`
+	MinHeight    int       `gorm:"column:min_height"`
+	MaxHeight    int       `gorm:"column:max_height"`
+	MeanHeight   float64   `gorm:"column:mean_height"`
+	StddevHeight float64   `gorm:"column:stddev_height"`
+	MedianHeight float64   `gorm:"column:median_height"`
+}
+
+// PortfolioFilter narrows down the estates aggregated into the portfolio stats, by owner and name
+// only as the estates don't have tags.
+// Nil fields are not filtered on, Name and Owner match the estates containing them, case insensitive.
+type PortfolioFilter struct {
+	Name  *string
+	Owner *string
+}
+
+// PortfolioEstate is an estate of the portfolio along with the height stats of its trees.
+type PortfolioEstate struct {
+	Estate
+	Stats TreeHeightStats
+}
+
+// HeightPercentiles is read from a JSON array of the percentile values.
+type HeightPercentiles []float64
+
+func (p *HeightPercentiles) Scan(value any) error {
+	switch value := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(value, p)
+	case string:
+		return json.Unmarshal([]byte(value), p)
+	default:
+		return fmt.Errorf("unsupported height percentiles type %T", value)
+	}
+}
+
+// TreeHeightBucket is the count of the trees with a height from Bucket times the bucket
+// size included to the next multiple excluded. Empty buckets are not returned.
+type TreeHeightBucket struct {
+	Bucket int `gorm:"column:bucket"`
+	Count  int `gorm:"column:count"`
+}
+
+// NearbyTree is a tree found around a position along with its distance from it, in plots.
+type NearbyTree struct {
+	Tree     `gorm:"embedded"`
+	Distance float64 `gorm:"column:distance"`
+}
+
+// Plot is the position of a plot of an estate.
+type Plot struct {
+	X int `gorm:"column:horizontal_position"`
+	Y int `gorm:"column:vertical_position"`
+}
+
+// PlotRun is a run of consecutive plots of row Y, from XFrom to XTo included.
+type PlotRun struct {
+	Y     int `gorm:"column:y"`
+	XFrom int `gorm:"column:x_from"`
+	XTo   int `gorm:"column:x_to"`
+}
+
+// GridCell is the aggregate of the trees in one square cell of plots,
+// CellX and CellY start from 0.
+type GridCell struct {
+	CellX        int     `gorm:"column:cell_x"`
+	CellY        int     `gorm:"column:cell_y"`
+	TreeCount    int     `gorm:"column:tree_count"`
+	PlantedPlots int     `gorm:"column:planted_plots"`
+	AvgHeight    float64 `gorm:"column:avg_height"`
+	MaxHeight    int     `gorm:"column:max_height"`
+}
+
+// Harvest is the fresh fruit bunches harvested from a tree in one harvest round.
+type Harvest struct {
+	ID          string    `gorm:"column:id;type:uuid;default:uuid_generate_v4();primaryKey"`
+	EstateID    string    `gorm:"column:estate_id;type:uuid;not null"`
+	TreeID      string    `gorm:"column:tree_id;type:uuid;not null"`
+	HarvestedOn time.Time `gorm:"column:harvested_on;type:date;not null"`
+	BunchCount  int       `gorm:"column:bunch_count;not null"`
+	WeightKg    float64   `gorm:"column:weight_kg;not null"`
+	CreatedAt   time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// YieldFilter narrows down the harvests counted in the yield of an estate.
+// Nil fields are not filtered on.
+type YieldFilter struct {
+	From  *time.Time
+	To    *time.Time
+	YFrom *int
+	YTo   *int
+}
+
+// Yield is the harvested yield of an estate or of one group of its harvests,
+// the group keys which are not part of the grouping are left nil.
+type Yield struct {
+	Month        *time.Time `gorm:"column:month"`
+	Y            *int       `gorm:"column:y"`
+	HarvestCount int        `gorm:"column:harvest_count"`
+	TreeCount    int        `gorm:"column:tree_count"`
+	BunchCount   int        `gorm:"column:bunch_count"`
+	WeightKg     float64    `gorm:"column:weight_kg"`
+}
+
+// HeightProfileLine is the height stats of the trees of a row or a column of an estate.
+type HeightProfileLine struct {
+	Position   int     `gorm:"column:position"`
+	TreeCount  int     `gorm:"column:tree_count"`
+	MinHeight  int     `gorm:"column:min_height"`
+	MaxHeight  int     `gorm:"column:max_height"`
+	MeanHeight float64 `gorm:"column:mean_height"`
+}
+
+// Division groups the blocks of an estate under a manager.
+type Division struct {
+	ID        string    `gorm:"column:id;type:uuid;default:uuid_generate_v4();primaryKey"`
+	EstateID  string    `gorm:"column:estate_id;type:uuid;not null"`
+	Name      string    `gorm:"column:name;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// Block is a rectangular range of plots of an estate, from and to included. The trees
+// belong to the block their position falls in, the blocks of an estate don't overlap.
+type Block struct {
+	ID         string    `gorm:"column:id;type:uuid;default:uuid_generate_v4();primaryKey"`
+	EstateID   string    `gorm:"column:estate_id;type:uuid;not null"`
+	DivisionID string    `gorm:"column:division_id;type:uuid;not null"`
+	Name       string    `gorm:"column:name;not null"`
+	XFrom      int       `gorm:"column:x_from;not null"`
+	XTo        int       `gorm:"column:x_to;not null"`
+	YFrom      int       `gorm:"column:y_from;not null"`
+	YTo        int       `gorm:"column:y_to;not null"`
+	CreatedAt  time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP;not null"`
+}
+
+// BlockSummary is a block along with its count of living trees.
+type BlockSummary struct {
+	Block     `gorm:"embedded"`
+	TreeCount int `gorm:"column:tree_count"`
+}
+
+// IdempotencyKey is a request sent with an Idempotency-Key and the response to replay when it is
+// retried, keyed by organisation so that two of them can't clash.
+type IdempotencyKey struct {
+	OrganisationID string `gorm:"column:organisation_id;type:uuid;primaryKey"`
+	Key            string `gorm:"column:idempotency_key;primaryKey"`
+	// RequestHash identifies the request the key was first sent with.
+	RequestHash string `gorm:"column:request_hash;not null"`
+	// StatusCode is nil while the request is still being processed.
+	StatusCode   *int      `gorm:"column:status_code"`
+	ContentType  string    `gorm:"column:content_type"`
+	ResponseBody []byte    `gorm:"column:response_body"`
+	CreatedAt    time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;not null"`
+	ExpiresAt    time.Time `gorm:"column:expires_at;not null"`
 }
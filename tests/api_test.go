@@ -16,6 +16,9 @@ import (
 
 const ApiUrl = "http://localhost:8080"
 
+// ApiKey is the admin API key docker-compose.yml configures.
+const ApiKey = "local-admin-key"
+
 func TestApi(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skip API tests")
@@ -32,6 +35,7 @@ func TestApi(t *testing.T) {
 				request, err := step.Request(t, ctx, &tc)
 				request.Header.Set("Content-Type", "application/json")
 				request.Header.Set("Accept", "application/json")
+				request.Header.Set("X-API-Key", ApiKey)
 				require.NoError(t, err)
 
 				// Send request